@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ulm0/dors/pkg/gen"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	coverageCfg  gen.Config
+	coverageJSON bool
+)
+
+// coverageCmd represents the coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage [dir]",
+	Short: "report the doc comment coverage of your go project",
+	Args:  cobra.MaximumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(coverageCmd)
+	coverageCmd.Flags().StringVarP(&coverageCfg.ConfigFile, "config", "f", "", "Config file to use, if empty .dors.yaml, .dors.yml, .dors.json, dors.yaml, dors.yml or dors.json is looked up in the root directory.")
+	coverageCmd.Flags().StringSliceVarP(&coverageCfg.ExcludePaths, "exclude-paths", "e", []string{}, "A list of folders to exclude from the report.")
+	coverageCmd.Flags().BoolVar(&coverageJSON, "json", false, "Print the report as JSON, listing the undocumented symbols.")
+	coverageCmd.Flags().Float64Var(&coverageCfg.CoverageThreshold, "threshold", 0, "Minimum percentage of exported symbols with a doc comment, below which the command fails.")
+
+	coverageCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		docGen := gen.New(coverageCfg)
+		if err := docGen.SetFlags(cmd.Flags()); err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		rootDir, err := getRootDir(args)
+		if err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		report, err := docGen.Coverage(cmd.Context(), rootDir)
+		if err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		if coverageJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return &exitError{code: exitFailure, err: fmt.Errorf("encoding report: %w", err)}
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		} else if err := report.WriteTable(cmd.OutOrStdout()); err != nil {
+			return &exitError{code: exitFailure, err: fmt.Errorf("printing report: %w", err)}
+		}
+
+		if !report.Passed() {
+			return &exitError{code: exitCoverage, err: fmt.Errorf("documentation coverage %.1f%% is below the threshold of %v%%", report.Total.Percent(), report.Threshold)}
+		}
+		if len(report.Errors) > 0 {
+			return &exitError{code: exitPackageErrors, err: fmt.Errorf("failed loading %d packages", len(report.Errors))}
+		}
+		return nil
+	}
+}
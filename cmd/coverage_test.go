@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestCoverageExitCode(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	// The package documents 3 of its 4 symbols, the package included.
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.21\n",
+		"m.go":            "// Package m is documented.\npackage m\n\n// A is documented.\nfunc A() {}\n\n// B is documented.\nfunc B() {}\n\nfunc C() {}\n",
+		"broken/b.go":     "// Package broken doesn't compile.\npackage broken\n\nvar X int = \"text\"\n",
+		".dors.yaml":      "excludePaths: [broken]\n",
+		"errors.dors.yml": "{}\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "no threshold", args: []string{"--threshold", "0"}, want: 0},
+		{name: "threshold reached", args: []string{"--threshold", "75"}, want: 0},
+		{name: "threshold missed", args: []string{"--threshold", "75.1"}, want: exitCoverage},
+		{name: "invalid threshold", args: []string{"--threshold", "101"}, want: exitFailure},
+		{name: "package errors", args: []string{"--threshold", "75", "-f", filepath.Join(dir, "errors.dors.yml")}, want: exitPackageErrors},
+		{name: "threshold before package errors", args: []string{"--threshold", "80", "-f", filepath.Join(dir, "errors.dors.yml")}, want: exitCoverage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The flags keep their value across the executions.
+			coverageCmd.Flags().Set("config", "")
+			rootCmd.SetArgs(append(append([]string{"coverage"}, tt.args...), dir))
+			rootCmd.SetOut(io.Discard)
+			err := rootCmd.ExecuteContext(context.Background())
+
+			code := 0
+			if err != nil {
+				code = exitFailure
+				var exitErr *exitError
+				if errors.As(err, &exitErr) {
+					code = exitErr.code
+				}
+			}
+			if code != tt.want {
+				t.Errorf("exit code = %d (%v), want %d", code, err, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/ulm0/dors/pkg/gen"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -13,26 +17,89 @@ var (
 
 // genCmd represents the gen command
 var genCmd = &cobra.Command{
-	Use:   "gen",
+	Use:   "gen [dir]",
 	Short: "generate docs for your go project",
+	Args:  cobra.MaximumNArgs(1),
 }
 
 func init() {
 	rootCmd.AddCommand(genCmd)
+	genCmd.Flags().StringVar(&cfg.Anchors, "anchors", gen.AnchorsHTML, "Anchors of the markdown headings: html adds an anchor named after the symbol, github, gitlab or commonmark link to the IDs those renderers generate.")
+	genCmd.Flags().BoolVar(&cfg.Check, "check", false, "Check that the documentation is up to date without writing it, printing a diff of the stale files.")
+	genCmd.Flags().StringVarP(&cfg.ConfigFile, "config", "f", "", "Config file to use, if empty .dors.yaml, .dors.yml, .dors.json, dors.yaml, dors.yml or dors.json is looked up in the root directory.")
+	genCmd.Flags().StringVar(&cfg.DeprecationNotice, "deprecation-notice", gen.DeprecationAlert, "Notice of the deprecated packages and symbols: alert renders a GitHub alert, quote a blockquote, none keeps the Deprecated: paragraph in their doc.")
+	genCmd.Flags().BoolVar(&cfg.HideDeprecated, "hide-deprecated", false, "Omit the deprecated packages, symbols and struct fields from the documentation.")
 	genCmd.Flags().StringSliceVarP(&includeSections, "include-sections", "i", []string{"constants", "factories", "functions", "methods", "types", "variables"}, "A list of sections to include in the documentation.")
+	genCmd.Flags().BoolVar(&cfg.Index, "index", false, "Render an Index section listing the symbols of each package with their signature.")
+	genCmd.Flags().BoolVar(&cfg.Inject, "inject", false, "Update the regions between <!-- dors:start --> and <!-- dors:end --> markers of the existing README.md files instead of writing DOCS.md files.")
+	genCmd.Flags().StringVar(&cfg.Format, "format", gen.FormatMarkdown, "Format of the documentation: markdown, json or html.")
 	genCmd.Flags().StringSliceVarP(&cfg.ExcludePaths, "exclude-paths", "e", []string{}, "A list of folders to exclude from the documentation.")
+	genCmd.Flags().BoolVar(&cfg.LegacyMarkdown, "legacy-markdown", false, "Render doc comments with the legacy parser instead of the Go 1.19 doc comment syntax.")
+	genCmd.Flags().BoolVar(&cfg.FieldTables, "field-tables", false, "Render a table with the fields of struct types, their tags, default values and docs.")
+	genCmd.Flags().BoolVar(&cfg.Implements, "implements", false, "List the interfaces each type implements and the types implementing each interface.")
+	genCmd.Flags().StringSliceVar(&cfg.Interfaces, "interfaces", []string{"error", "fmt.Stringer", "io.Reader", "io.Writer"}, "Interfaces from outside the project checked for the implements sections.")
+	genCmd.Flags().BoolVar(&cfg.LinkTypes, "link-types", false, "Render declarations as HTML blocks where the referenced types link to their documentation.")
+	genCmd.Flags().StringVar(&cfg.OutDir, "out-dir", "", "Directory the documentation is written to, relative to the root directory, mirroring the package tree. If empty it is written next to the sources, or to site with --format html. --out is an alias.")
+	genCmd.Flags().StringVar(&cfg.OutputName, "output-name", "", "Name of the documentation files, such as README.md or index.md. If empty DOCS.md, DOCS.json or index.html is used.")
+	genCmd.Flags().BoolVar(&cfg.SingleFile, "single-file", false, "Render every package into a single API.md file with a table of contents.")
 	genCmd.Flags().BoolVarP(&cfg.PrintSource, "print-source", "p", false, "Print source code for each symbol.")
+	genCmd.Flags().BoolVar(&cfg.PromotedMethods, "promoted-methods", false, "List the methods types gain through their embedded fields.")
 	genCmd.Flags().BoolVarP(&cfg.Recursive, "recursive", "r", true, "Read all files in the package and generate the documentation. It can be used in combination with include, and exclude.")
 	genCmd.Flags().BoolVarP(&cfg.RespectCase, "respect-case", "c", true, "Respect case when matching symbols.")
 	genCmd.Flags().BoolVarP(&cfg.Short, "short", "s", false, "One-line representation for each symbol.")
+	genCmd.Flags().BoolVarP(&cfg.SkipExamples, "skip-examples", "x", false, "SkipExamples will omit the examples from the README.")
 	genCmd.Flags().BoolVarP(&cfg.SkipSubPkgs, "skip-sub-pkgs", "k", false, "SkipSubPackages will omit the sub packages section from the README.")
+	genCmd.Flags().StringVar(&cfg.TemplateDir, "template-dir", "", "Directory with *.gotmpl files overriding the built-in templates, relative to the root directory.")
 	genCmd.Flags().StringVarP(&cfg.Title, "title", "t", "", "Title for the documentation, if empty the package name is used.")
+	genCmd.Flags().BoolVar(&cfg.Undocumented, "undocumented", false, "List the exported symbols without a doc comment in an Undocumented section.")
 	genCmd.Flags().BoolVarP(&cfg.Unexported, "unexported", "u", false, "Include unexported symbols.")
+	genCmd.Flags().BoolVar(&cfg.ValueTables, "value-tables", false, "Render grouped constants and variables as a table of names, values and descriptions.")
+	genCmd.Flags().SetNormalizeFunc(outAlias)
 
-	genCmd.Run = func(cmd *cobra.Command, args []string) {
+	genCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		cfg.IncludeSections = make([]string, len(includeSections))
 		copy(cfg.IncludeSections, includeSections)
 		docGen := gen.New(cfg)
-		docGen.Run(cmd, args)
+		if err := docGen.SetFlags(cmd.Flags()); err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		rootDir, err := getRootDir(args)
+		if err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		result, err := docGen.Generate(cmd.Context(), rootDir)
+		if err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		for _, f := range result.Stale {
+			fmt.Fprint(cmd.OutOrStdout(), f.Diff)
+		}
+		if len(result.Stale) > 0 {
+			return &exitError{code: exitStale, err: fmt.Errorf("%d files are out of date, run dors gen to update them", len(result.Stale))}
+		}
+
+		if len(result.Errors) > 0 {
+			return &exitError{code: exitPackageErrors, err: fmt.Errorf("failed documenting %d packages", len(result.Errors))}
+		}
+		return nil
+	}
+}
+
+// outAlias makes --out an alias of --out-dir.
+func outAlias(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "out" {
+		name = "out-dir"
+	}
+	return pflag.NormalizedName(name)
+}
+
+// getRootDir retrieves the root directory from command-line arguments or defaults to the current working directory.
+func getRootDir(args []string) (string, error) {
+	if len(args) == 0 {
+		return os.Getwd()
 	}
+	return args[0], nil
 }
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ulm0/dors/pkg/gen"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the lint command.
+const (
+	lintFormatText   = "text"
+	lintFormatJSON   = "json"
+	lintFormatGitHub = "github"
+)
+
+var (
+	lintCfg    gen.Config
+	lintFormat string
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [dir]",
+	Short: "report common problems in the doc comments of your go project",
+	Args:  cobra.MaximumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringVarP(&lintCfg.ConfigFile, "config", "f", "", "Config file to use, if empty .dors.yaml, .dors.yml, .dors.json, dors.yaml, dors.yml or dors.json is looked up in the root directory.")
+	lintCmd.Flags().StringSliceVar(&lintCfg.Lint.Disable, "disable", []string{}, "Rules not to run.")
+	lintCmd.Flags().StringSliceVar(&lintCfg.Lint.Enable, "enable", []string{}, "Rules to run, if empty all of them are.")
+	lintCmd.Flags().StringSliceVarP(&lintCfg.ExcludePaths, "exclude-paths", "e", []string{}, "A list of folders to exclude from the linting.")
+	lintCmd.Flags().StringVar(&lintFormat, "output-format", lintFormatText, "Format of the issues: text prints file:line:column positions, json a report, github workflow commands annotating pull requests.")
+
+	lintCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		switch lintFormat {
+		case lintFormatText, lintFormatJSON, lintFormatGitHub:
+		default:
+			return &exitError{code: exitFailure, err: fmt.Errorf("unknown output format %q, expected %s, %s or %s", lintFormat, lintFormatText, lintFormatJSON, lintFormatGitHub)}
+		}
+
+		docGen := gen.New(lintCfg)
+		if err := docGen.SetFlags(cmd.Flags()); err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		rootDir, err := getRootDir(args)
+		if err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		report, err := docGen.Lint(cmd.Context(), rootDir)
+		if err != nil {
+			return &exitError{code: exitFailure, err: err}
+		}
+
+		switch lintFormat {
+		case lintFormatJSON:
+			var data []byte
+			data, err = json.MarshalIndent(report, "", "  ")
+			if err == nil {
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			}
+		case lintFormatGitHub:
+			err = report.WriteGitHub(cmd.OutOrStdout())
+		default:
+			err = report.WriteText(cmd.OutOrStdout())
+		}
+		if err != nil {
+			return &exitError{code: exitFailure, err: fmt.Errorf("printing report: %w", err)}
+		}
+
+		if len(report.Issues) > 0 {
+			return &exitError{code: exitLint, err: fmt.Errorf("found %d problems in the doc comments", len(report.Issues))}
+		}
+		if len(report.Errors) > 0 {
+			return &exitError{code: exitPackageErrors, err: fmt.Errorf("failed loading %d packages", len(report.Errors))}
+		}
+		return nil
+	}
+}
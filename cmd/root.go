@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
 
+	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 )
 
@@ -10,6 +14,9 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "dors",
 	Short: "simple doc generator for your go projects",
+	// Errors are logged by Execute along with the exit code they map to.
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	//	Long: `A longer description that spans multiple lines and likely contains
 	//examples and usage of using your application. For example:
 	//
@@ -24,8 +31,47 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err == nil {
+		return
 	}
+
+	code := exitFailure
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.code
+	}
+	log.Error(err)
+	os.Exit(code)
+}
+
+// Exit codes of the dors command.
+const (
+	// exitFailure is returned when the documentation couldn't be generated.
+	exitFailure = 1
+	// exitStale is returned by gen --check when the documentation is out of date.
+	exitStale = 2
+	// exitPackageErrors is returned when some packages couldn't be documented.
+	exitPackageErrors = 3
+	// exitCoverage is returned by coverage when the documentation coverage is
+	// below the threshold.
+	exitCoverage = 4
+	// exitLint is returned by lint when it finds problems in the doc comments.
+	exitLint = 5
+)
+
+// exitError is an error that makes the command exit with a specific code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
 }
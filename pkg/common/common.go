@@ -1,18 +1,38 @@
 package common
 
 import (
+	"go/ast"
 	"go/doc"
 	"go/token"
+	"go/types"
 )
 
 // Pkg is used to store the package information.
 type Pkg struct {
+	// DocFile is the name of the documentation file of the package, empty when
+	// it has none, like the packages whose README.md has no markers to inject
+	// the documentation into.
 	DocFile  string
 	FilesSet *token.FileSet
 	Module   string
 	Package  *doc.Package
 	Path     string
 	SubPkgs  []*Pkg
+	// Files holds the syntax of the package files, without the _test.go ones.
+	Files []*ast.File
+	// Types is the type-checked package.
+	Types *types.Package
+	// TypesInfo holds the type information of the package syntax.
+	TypesInfo *types.Info
+	// Strings holds the output of the String method of the constants whose
+	// type has one, when it can be determined statically.
+	Strings map[types.Object]string
+	// Fields maps the struct fields declared in the packages of the module to
+	// their syntax, including the fields of unexported types.
+	Fields map[types.Object]*ast.Field
+	// Docs maps the files, declarations, specs and fields of Files to their
+	// doc comment, which go/doc removes from the syntax.
+	Docs map[ast.Node]*ast.CommentGroup
 }
 
 func (p *Pkg) Link() string {
@@ -22,3 +42,8 @@ func (p *Pkg) Link() string {
 func (p *Pkg) Doc() string {
 	return p.Package.Doc
 }
+
+// Synopsis returns the first sentence of the package documentation.
+func (p *Pkg) Synopsis() string {
+	return p.Package.Synopsis(p.Package.Doc)
+}
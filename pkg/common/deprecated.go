@@ -0,0 +1,27 @@
+package common
+
+import "strings"
+
+// Deprecation returns the text of the "Deprecated: " paragraph of a doc
+// comment, and whether it has one, following the Go convention.
+func Deprecation(doc string) (string, bool) {
+	for _, para := range strings.Split(doc, "\n\n") {
+		text, ok := strings.CutPrefix(strings.TrimSpace(para), "Deprecated: ")
+		if ok {
+			return strings.Join(strings.Fields(text), " "), true
+		}
+	}
+	return "", false
+}
+
+// WithoutDeprecation returns doc without its "Deprecated: " paragraph.
+func WithoutDeprecation(doc string) string {
+	paras := strings.Split(doc, "\n\n")
+	kept := paras[:0]
+	for _, para := range paras {
+		if !strings.HasPrefix(strings.TrimSpace(para), "Deprecated: ") {
+			kept = append(kept, para)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
@@ -0,0 +1,180 @@
+package common
+
+import (
+	"go/doc"
+	"go/doc/comment"
+	"path"
+	"strings"
+)
+
+// PkgGoDevURL is the base URL of the links to packages outside the project.
+const PkgGoDevURL = "https://pkg.go.dev"
+
+// Index is a project-wide index of the documented symbols, used to resolve
+// references to them.
+type Index struct {
+	pkgs map[string]*Pkg
+	syms map[string]map[string]bool
+	// singleFile is set when every package is documented in the same file.
+	singleFile bool
+	// pkgGoDev is set when every link points to pkg.go.dev.
+	pkgGoDev bool
+}
+
+// NewIndex indexes the symbols documented in pkgs.
+func NewIndex(pkgs []*Pkg) *Index {
+	idx := &Index{
+		pkgs: make(map[string]*Pkg, len(pkgs)),
+		syms: make(map[string]map[string]bool, len(pkgs)),
+	}
+
+	for _, p := range pkgs {
+		syms := make(map[string]bool)
+		addValues := func(values []*doc.Value) {
+			for _, v := range values {
+				for _, name := range v.Names {
+					syms[name] = true
+				}
+			}
+		}
+
+		addValues(p.Package.Consts)
+		addValues(p.Package.Vars)
+		for _, f := range p.Package.Funcs {
+			syms[f.Name] = true
+		}
+		for _, t := range p.Package.Types {
+			syms[t.Name] = true
+			addValues(t.Consts)
+			addValues(t.Vars)
+			for _, f := range t.Funcs {
+				syms[f.Name] = true
+			}
+			for _, m := range t.Methods {
+				syms[SymbolID(t.Name, m.Name)] = true
+			}
+		}
+
+		idx.pkgs[p.Package.ImportPath] = p
+		idx.syms[p.Package.ImportPath] = syms
+	}
+
+	return idx
+}
+
+// SetSingleFile makes the links point to the sections of a single document
+// holding every package, whose anchors are given by AnchorID.
+func (i *Index) SetSingleFile(singleFile bool) {
+	i.singleFile = singleFile
+}
+
+// SetPkgGoDevLinks makes every link point to pkg.go.dev, for documents read
+// apart from the generated files, where links relative to them are meaningless.
+func (i *Index) SetPkgGoDevLinks(pkgGoDev bool) {
+	i.pkgGoDev = pkgGoDev
+}
+
+// Lookup returns the package documented at importPath, and whether it
+// documents the symbol. An empty name refers to the package itself.
+func (i *Index) Lookup(importPath, recv, name string) (*Pkg, bool) {
+	p, ok := i.pkgs[importPath]
+	if !ok {
+		return nil, false
+	}
+	if name == "" {
+		return p, true
+	}
+	return p, i.syms[importPath][SymbolID(recv, name)]
+}
+
+// LinkURL returns the URL of a doc link found in the documentation of from.
+// Symbols documented in the project link to the anchor of their heading, unless
+// SetPkgGoDevLinks is enabled, the rest link to pkg.go.dev, like the symbols of
+// the packages without a documentation file. It reports false when the link points to a package
+// of the project that doesn't document the symbol.
+func (i *Index) LinkURL(from *Pkg, link *comment.DocLink) (string, bool) {
+	importPath := link.ImportPath
+	if importPath == "" {
+		importPath = from.Package.ImportPath
+	}
+
+	target, ok := i.Lookup(importPath, link.Recv, link.Name)
+	if target == nil {
+		return link.DefaultURL(PkgGoDevURL), true
+	}
+	if i.pkgGoDev || target.DocFile == "" {
+		// Links to the symbols of from hold no import path. The packages
+		// without a documentation file are only documented by pkg.go.dev.
+		qualified := *link
+		qualified.ImportPath = importPath
+		return qualified.DefaultURL(PkgGoDevURL), ok
+	}
+
+	if i.singleFile {
+		return "#" + AnchorID(target, link.Recv, link.Name), ok
+	}
+
+	url := RelLink(from, target)
+	if link.Name != "" {
+		url += "#" + SymbolID(link.Recv, link.Name)
+	}
+	return url, ok
+}
+
+// RelLink returns the link to the documentation of target relative to the
+// documentation of from, or an empty string when both are the same.
+func RelLink(from, target *Pkg) string {
+	if from == target {
+		return ""
+	}
+
+	fromParts := splitPath(from.Path)
+	targetParts := splitPath(target.Path)
+	common := 0
+	for common < len(fromParts) && common < len(targetParts) && fromParts[common] == targetParts[common] {
+		common++
+	}
+
+	parts := make([]string, 0, len(fromParts)-common+len(targetParts)-common+1)
+	for range fromParts[common:] {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, targetParts[common:]...)
+	parts = append(parts, target.DocFile)
+	return path.Join(parts...)
+}
+
+// SymbolID returns the anchor ID of a symbol, the method M of type T is
+// identified as "T.M", like in pkg.go.dev.
+func SymbolID(recv, name string) string {
+	if recv == "" {
+		return name
+	}
+	return recvTypeName(recv) + "." + name
+}
+
+// AnchorID returns the anchor ID of a symbol of pkg in a document holding
+// several packages, the symbol ID qualified with the import path of the package
+// like a doc link: "example.com/m/pkg.T.M". An empty name identifies the package.
+func AnchorID(pkg *Pkg, recv, name string) string {
+	if name == "" {
+		return pkg.Package.ImportPath
+	}
+	return pkg.Package.ImportPath + "." + SymbolID(recv, name)
+}
+
+// recvTypeName strips the pointer and the type parameters from a receiver.
+func recvTypeName(recv string) string {
+	recv = strings.TrimPrefix(recv, "*")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return recv
+}
+
+func splitPath(p string) []string {
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
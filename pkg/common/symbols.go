@@ -0,0 +1,154 @@
+package common
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/token"
+)
+
+// Kinds of the exported symbols of a package.
+const (
+	KindPackage = "package"
+	KindConst   = "const"
+	KindVar     = "var"
+	KindFunc    = "func"
+	KindType    = "type"
+	KindField   = "field"
+	KindMethod  = "method"
+)
+
+// Kinds lists the kinds of the exported symbols in the order they're documented.
+var Kinds = []string{KindPackage, KindConst, KindVar, KindFunc, KindType, KindField, KindMethod}
+
+// Symbol is an exported symbol of a package, or the package itself.
+type Symbol struct {
+	// Kind is one of Kinds.
+	Kind string
+	// ID identifies the symbol like SymbolID, the fields and the methods of
+	// interfaces are identified as "T.Name". It's empty for the package.
+	ID string
+	// Anchor is the ID of the heading documenting the symbol, the one of its
+	// type for the fields and the methods of interfaces. It's empty for the package.
+	Anchor string
+	// Doc is the doc comment of the symbol, or the line comment of the fields
+	// and the values declared without one. A value declared in a group
+	// without comments of its own has the doc of the group.
+	Doc string
+	// Pos is the position of the name of the symbol.
+	Pos token.Pos
+}
+
+// ExportedSymbols returns the package and its exported symbols, in the order
+// they're documented. The methods promoted from embedded types and the
+// embedded fields are left out.
+func (p *Pkg) ExportedSymbols() []Symbol {
+	d := p.Package
+	syms := []Symbol{{Kind: KindPackage, Doc: d.Doc, Pos: p.packageClause()}}
+
+	addValues := func(values []*doc.Value, kind string) {
+		for _, v := range values {
+			for _, spec := range v.Decl.Specs {
+				spec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				text := firstText(spec.Doc.Text(), spec.Comment.Text(), v.Doc)
+				for _, name := range spec.Names {
+					if name.IsExported() {
+						syms = append(syms, Symbol{Kind: kind, ID: name.Name, Anchor: name.Name, Doc: text, Pos: name.Pos()})
+					}
+				}
+			}
+		}
+	}
+	addFuncs := func(funcs []*doc.Func, recv string) {
+		for _, f := range funcs {
+			if f.Level > 0 || !token.IsExported(f.Name) {
+				continue
+			}
+			kind := KindFunc
+			if f.Recv != "" {
+				kind = KindMethod
+			}
+			id := SymbolID(recv, f.Name)
+			syms = append(syms, Symbol{Kind: kind, ID: id, Anchor: id, Doc: f.Doc, Pos: f.Decl.Name.Pos()})
+		}
+	}
+
+	addValues(d.Consts, KindConst)
+	addValues(d.Vars, KindVar)
+	addFuncs(d.Funcs, "")
+	for _, t := range d.Types {
+		// The values and factories of unexported types are documented with
+		// them when unexported symbols are.
+		addValues(t.Consts, KindConst)
+		addValues(t.Vars, KindVar)
+		addFuncs(t.Funcs, "")
+		if !token.IsExported(t.Name) {
+			continue
+		}
+
+		spec := typeSpec(t)
+		if spec == nil {
+			continue
+		}
+		syms = append(syms, Symbol{Kind: KindType, ID: t.Name, Anchor: t.Name, Doc: t.Doc, Pos: spec.Name.Pos()})
+
+		var members *ast.FieldList
+		kind := KindField
+		switch typ := spec.Type.(type) {
+		case *ast.StructType:
+			members = typ.Fields
+		case *ast.InterfaceType:
+			members, kind = typ.Methods, KindMethod
+		}
+		if members != nil {
+			for _, field := range members.List {
+				text := firstText(field.Doc.Text(), field.Comment.Text())
+				for _, name := range field.Names {
+					if name.IsExported() {
+						syms = append(syms, Symbol{Kind: kind, ID: t.Name + "." + name.Name, Anchor: t.Name, Doc: text, Pos: name.Pos()})
+					}
+				}
+			}
+		}
+		addFuncs(t.Methods, t.Name)
+	}
+	return syms
+}
+
+// packageClause returns the position of the package clause holding the
+// package doc, or of the first file when there is none. The doc is looked up
+// in Docs, go/doc removes it from the files.
+func (p *Pkg) packageClause() token.Pos {
+	var pos token.Pos
+	for _, f := range p.Files {
+		if p.Docs[f] != nil {
+			return f.Package
+		}
+		if pos == token.NoPos {
+			pos = f.Package
+		}
+	}
+	return pos
+}
+
+// typeSpec returns the spec declaring t.
+func typeSpec(t *doc.Type) *ast.TypeSpec {
+	for _, spec := range t.Decl.Specs {
+		if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.Name == t.Name {
+			return spec
+		}
+	}
+	return nil
+}
+
+// firstText returns the first of texts that isn't empty.
+func firstText(texts ...string) string {
+	for _, text := range texts {
+		if text != "" {
+			return text
+		}
+	}
+	return ""
+}
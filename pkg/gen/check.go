@@ -0,0 +1,65 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// checkDocs compares the rendered files with the ones in the root directory
+// without modifying them, and returns the stale files along with their diff.
+func checkDocs(rootDir string, files []docFile) ([]StaleFile, error) {
+	var stale []StaleFile
+	for _, f := range files {
+		fromFile := "a/" + f.Path
+		current, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(f.Path)))
+		if errors.Is(err, fs.ErrNotExist) {
+			fromFile = "/dev/null"
+		} else if err != nil {
+			return stale, fmt.Errorf("reading documentation: %w", err)
+		}
+
+		if bytes.Equal(current, f.Content) {
+			continue
+		}
+
+		log.Warn("Documentation is out of date", "path", f.Path)
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(string(current)),
+			B:        splitLines(string(f.Content)),
+			FromFile: fromFile,
+			ToFile:   "b/" + f.Path,
+			Context:  3,
+		})
+		if err != nil {
+			return stale, fmt.Errorf("diffing %s: %w", f.Path, err)
+		}
+
+		stale = append(stale, StaleFile{Path: f.Path, Diff: diff})
+	}
+	return stale, nil
+}
+
+// splitLines splits s into lines ending with their line break. Unlike
+// difflib.SplitLines it doesn't add an empty line after the last line break.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		// Keep the last line apart from the next one in the diff.
+		lines[len(lines)-1] = last + "\n"
+	}
+	return lines
+}
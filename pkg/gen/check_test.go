@@ -0,0 +1,64 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "\n", want: []string{"\n"}},
+		{in: "a\nb\n", want: []string{"a\n", "b\n"}},
+		{in: "a\nb", want: []string{"a\n", "b\n"}},
+		{in: "a\n\n", want: []string{"a\n", "\n"}},
+	}
+	for _, tt := range tests {
+		if got := splitLines(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDocs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "DOCS.md"), []byte("# Title\n\nOld text.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "UP.md"), []byte("Same.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := checkDocs(dir, []docFile{
+		{Path: "DOCS.md", Content: []byte("# Title\n\nNew text.\n")},
+		{Path: "UP.md", Content: []byte("Same.\n")},
+		{Path: "sub/DOCS.md", Content: []byte("New file.\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []StaleFile{
+		{
+			Path: "DOCS.md",
+			Diff: "--- a/DOCS.md\n+++ b/DOCS.md\n@@ -1,3 +1,3 @@\n # Title\n \n-Old text.\n+New text.\n",
+		},
+		{
+			Path: "sub/DOCS.md",
+			Diff: "--- /dev/null\n+++ b/sub/DOCS.md\n@@ -0,0 +1 @@\n+New file.\n",
+		},
+	}
+	if !slices.Equal(stale, want) {
+		t.Errorf("checkDocs() = %q, want %q", stale, want)
+	}
+
+	// The files on disk are left alone.
+	if _, err := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(err) {
+		t.Errorf("checkDocs() created the missing directory: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are the config files looked up in the root directory, in order of precedence.
+var configFileNames = []string{".dors.yaml", ".dors.yml", ".dors.json", "dors.yaml", "dors.yml", "dors.json"}
+
+// Override changes the configuration of the packages matching its path.
+//
+// In a config file it is written as the path followed by any of the Config keys:
+//
+//	overrides:
+//	  - path: internal/...
+//	    includeSections: [types]
+//	    unexported: true
+type Override struct {
+	// Path of the packages relative to the root directory, a trailing "/..."
+	// also matches every package below it.
+	Path string
+	// settings holds the Config keys changed by the override.
+	settings []byte
+}
+
+// UnmarshalJSON reads the path of the override and validates the rest of its keys.
+func (o *Override) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	rawPath, ok := fields["path"]
+	if !ok {
+		return errors.New("override is missing the path key")
+	}
+	if err := json.Unmarshal(rawPath, &o.Path); err != nil {
+		return fmt.Errorf("override path: %w", err)
+	}
+	delete(fields, "path")
+
+	if _, ok := fields["overrides"]; ok {
+		return fmt.Errorf("override %s: overrides cannot be nested", o.Path)
+	}
+
+	settings, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := decodeConfig(settings, &Config{}); err != nil {
+		return fmt.Errorf("override %s: %w", o.Path, err)
+	}
+	o.settings = settings
+	return nil
+}
+
+// matches reports whether the override applies to the package at pkgPath.
+func (o Override) matches(pkgPath string) bool {
+	p, recursive := strings.CutSuffix(o.Path, "/...")
+	if o.Path == "..." {
+		p, recursive = "", true
+	}
+	p = path.Clean(p)
+	if p == "." {
+		p = ""
+	}
+
+	if pkgPath == p {
+		return true
+	}
+	return recursive && (p == "" || strings.HasPrefix(pkgPath, p+"/"))
+}
+
+// SetFlags gives the flags changed on the command line precedence over the
+// config file and its overrides. The flags must be bound to the Config given to New.
+func (g *Gen) SetFlags(flags *pflag.FlagSet) error {
+	pinned, err := changedFlags(g.config, flags)
+	if err != nil {
+		return err
+	}
+	g.pinned = pinned
+	return nil
+}
+
+// loadConfig merges the project config file into the configuration.
+func (g *Gen) loadConfig(rootDir string) error {
+	file := g.config.ConfigFile
+	if file == "" {
+		file = findConfigFile(rootDir)
+		if file == "" {
+			return nil
+		}
+	}
+
+	log.Info("Using config file", "path", file)
+	data, err := readConfigFile(file)
+	if err != nil {
+		return err
+	}
+
+	cfg := g.config.clone()
+	if err := decodeConfig(data, &cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", file, err)
+	}
+	if g.pinned != nil {
+		if err := decodeConfig(g.pinned, &cfg); err != nil {
+			return fmt.Errorf("applying flags: %w", err)
+		}
+	}
+
+	g.config = cfg
+	return nil
+}
+
+// configFor returns the configuration for the package at pkgPath with the
+// matching overrides applied.
+func (g *Gen) configFor(pkgPath string) Config {
+	cfg := g.config.clone()
+	overridden := false
+	for _, o := range g.config.Overrides {
+		if !o.matches(pkgPath) {
+			continue
+		}
+		// Settings were validated when the config file was loaded.
+		if err := decodeConfig(o.settings, &cfg); err != nil {
+			log.Error("Failed to apply override", "path", o.Path, "error", err)
+		}
+		overridden = true
+	}
+
+	if overridden && g.pinned != nil {
+		if err := decodeConfig(g.pinned, &cfg); err != nil {
+			log.Error("Failed to apply flags", "error", err)
+		}
+	}
+	return cfg
+}
+
+// clone returns a copy of the configuration that doesn't share its slices.
+func (c Config) clone() Config {
+	c.IncludeSections = slices.Clone(c.IncludeSections)
+	c.ExcludePaths = slices.Clone(c.ExcludePaths)
+	c.Interfaces = slices.Clone(c.Interfaces)
+	c.Overrides = slices.Clone(c.Overrides)
+	c.Lint.Enable = slices.Clone(c.Lint.Enable)
+	c.Lint.Disable = slices.Clone(c.Lint.Disable)
+	return c
+}
+
+// findConfigFile returns the first config file found in dir, or an empty string.
+func findConfigFile(dir string) string {
+	for _, name := range configFileNames {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// readConfigFile reads a YAML or JSON config file and returns its content as JSON,
+// so that both formats are decoded using the Config json tags.
+func readConfigFile(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		var v map[string]any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", file, err)
+		}
+		if v == nil {
+			return []byte("{}"), nil
+		}
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", file, err)
+		}
+	}
+	return data, nil
+}
+
+// decodeConfig decodes data on top of cfg, rejecting unknown keys.
+func decodeConfig(data []byte, cfg *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(cfg)
+}
+
+// changedFlags returns the Config values of the flags set on the command line,
+// keyed like the Config json tags.
+func changedFlags(cfg Config, flags *pflag.FlagSet) ([]byte, error) {
+	if flags == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]json.RawMessage)
+	flags.Visit(func(f *pflag.Flag) {
+		key := flagKey(f.Name)
+		if v, ok := values[key]; ok {
+			changed[key] = v
+		}
+	})
+	return json.Marshal(changed)
+}
+
+// flagKeys holds the json keys of the flags named differently. The rules
+// given as flags replace the whole lint settings of the config file.
+var flagKeys = map[string]string{
+	"threshold": "coverageThreshold",
+	"enable":    "lint",
+	"disable":   "lint",
+}
+
+// flagKey converts a flag name such as "skip-sub-pkgs" into its json key "skipSubPkgs".
+func flagKey(name string) string {
+	if key, ok := flagKeys[name]; ok {
+		return key
+	}
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
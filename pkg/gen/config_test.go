@@ -0,0 +1,319 @@
+package gen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// newTestFlags binds a few flags of the commands to cfg, one of each kind.
+func newTestFlags(cfg *Config) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("gen", pflag.ContinueOnError)
+	flags.StringVarP(&cfg.Title, "title", "t", "", "")
+	flags.BoolVarP(&cfg.Unexported, "unexported", "u", false, "")
+	flags.BoolVarP(&cfg.SkipSubPkgs, "skip-sub-pkgs", "k", false, "")
+	flags.StringSliceVarP(&cfg.ExcludePaths, "exclude-paths", "e", []string{}, "")
+	flags.Float64Var(&cfg.CoverageThreshold, "threshold", 0, "")
+	flags.StringSliceVar(&cfg.Lint.Enable, "enable", []string{}, "")
+	return flags
+}
+
+// newTestGen returns a Gen with the config files written to a temporary root
+// directory and the flags parsed from args, along with that directory.
+func newTestGen(t *testing.T, files map[string]string, args []string) (*Gen, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+
+	var cfg Config
+	flags := newTestFlags(&cfg)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	g := New(cfg)
+	if err := g.SetFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+	return g, dir
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		args  []string
+		// configFile is set as the ConfigFile, relative to the root directory.
+		configFile string
+		want       Config
+		wantErr    string
+	}{
+		{
+			name: "no config file",
+			args: []string{"--title", "Flag"},
+			want: Config{Title: "Flag", ExcludePaths: []string{}, Lint: LintConfig{Enable: []string{}}},
+		},
+		{
+			name:  "yaml",
+			files: map[string]string{".dors.yaml": "title: File\nunexported: true\nexcludePaths: [internal]\nlint:\n  disable: [period]\n"},
+			want:  Config{Title: "File", Unexported: true, ExcludePaths: []string{"internal"}, Lint: LintConfig{Enable: []string{}, Disable: []string{"period"}}},
+		},
+		{
+			name:  "json",
+			files: map[string]string{"dors.json": `{"title": "File", "coverageThreshold": 80}`},
+			want:  Config{Title: "File", CoverageThreshold: 80, ExcludePaths: []string{}, Lint: LintConfig{Enable: []string{}}},
+		},
+		{
+			name:  "empty yaml",
+			files: map[string]string{".dors.yml": ""},
+			want:  Config{ExcludePaths: []string{}, Lint: LintConfig{Enable: []string{}}},
+		},
+		{
+			name: "file precedence",
+			files: map[string]string{
+				".dors.yaml": "title: Hidden\n",
+				".dors.json": `{"title": "Hidden JSON"}`,
+				"dors.yaml":  "title: Plain\n",
+			},
+			want: Config{Title: "Hidden", ExcludePaths: []string{}, Lint: LintConfig{Enable: []string{}}},
+		},
+		{
+			name: "explicit config file",
+			files: map[string]string{
+				".dors.yaml":     "title: Default\n",
+				"conf/dors.yaml": "title: Explicit\n",
+			},
+			configFile: "conf/dors.yaml",
+			want:       Config{Title: "Explicit", ExcludePaths: []string{}, Lint: LintConfig{Enable: []string{}}},
+		},
+		{
+			name:  "flags take precedence",
+			files: map[string]string{".dors.yaml": "title: File\nunexported: true\nskipSubPkgs: true\nexcludePaths: [a]\n"},
+			args:  []string{"-t", "Flag", "--unexported=false", "-e", "b,c"},
+			want:  Config{Title: "Flag", SkipSubPkgs: true, ExcludePaths: []string{"b", "c"}, Lint: LintConfig{Enable: []string{}}},
+		},
+		{
+			name:  "flags with another key",
+			files: map[string]string{".dors.yaml": "coverageThreshold: 50\nlint:\n  enable: [period]\n  disable: [heading]\n"},
+			args:  []string{"--threshold", "75", "--enable", "doc-name"},
+			// The rules given as flags replace the lint settings of the file.
+			want: Config{CoverageThreshold: 75, ExcludePaths: []string{}, Lint: LintConfig{Enable: []string{"doc-name"}}},
+		},
+		{
+			name:    "unknown key",
+			files:   map[string]string{".dors.yaml": "titel: File\n"},
+			wantErr: `unknown field "titel"`,
+		},
+		{
+			name:    "unknown nested key",
+			files:   map[string]string{".dors.json": `{"lint": {"enabled": ["period"]}}`},
+			wantErr: `unknown field "enabled"`,
+		},
+		{
+			name:    "wrong type",
+			files:   map[string]string{".dors.yaml": "unexported: yes please\n"},
+			wantErr: "invalid config file",
+		},
+		{
+			name:    "invalid yaml",
+			files:   map[string]string{".dors.yaml": "title: [\n"},
+			wantErr: "invalid config file",
+		},
+		{
+			name:       "missing config file",
+			configFile: "missing.yaml",
+			wantErr:    "reading config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, dir := newTestGen(t, tt.files, tt.args)
+			if tt.configFile != "" {
+				g.config.ConfigFile = dir + "/" + tt.configFile
+				tt.want.ConfigFile = g.config.ConfigFile
+			}
+
+			err := g.loadConfig(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(g.config, tt.want) {
+				t.Errorf("loadConfig() config = %+v, want %+v", g.config, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFor(t *testing.T) {
+	const file = `title: Project
+includeSections: [types, functions]
+overrides:
+  - path: internal/...
+    unexported: true
+    includeSections: [types]
+  - path: internal/secret
+    title: Secret
+  - path: cmd
+    skipSubPkgs: true
+  - path: ./tools/
+    title: Tools
+`
+	tests := []struct {
+		name    string
+		args    []string
+		pkgPath string
+		want    Config
+	}{
+		{
+			name:    "root",
+			pkgPath: "",
+			want:    Config{Title: "Project", IncludeSections: []string{"types", "functions"}},
+		},
+		{
+			name:    "recursive override",
+			pkgPath: "internal",
+			want:    Config{Title: "Project", IncludeSections: []string{"types"}, Unexported: true},
+		},
+		{
+			name:    "overrides in order",
+			pkgPath: "internal/secret",
+			want:    Config{Title: "Secret", IncludeSections: []string{"types"}, Unexported: true},
+		},
+		{
+			name:    "prefix of another path",
+			pkgPath: "internals",
+			want:    Config{Title: "Project", IncludeSections: []string{"types", "functions"}},
+		},
+		{
+			name:    "exact path",
+			pkgPath: "cmd",
+			want:    Config{Title: "Project", IncludeSections: []string{"types", "functions"}, SkipSubPkgs: true},
+		},
+		{
+			name:    "below an exact path",
+			pkgPath: "cmd/sub",
+			want:    Config{Title: "Project", IncludeSections: []string{"types", "functions"}},
+		},
+		{
+			name:    "cleaned path",
+			pkgPath: "tools",
+			want:    Config{Title: "Tools", IncludeSections: []string{"types", "functions"}},
+		},
+		{
+			name:    "flags over overrides",
+			args:    []string{"--unexported=false", "-t", "Flag"},
+			pkgPath: "internal/secret",
+			want:    Config{Title: "Flag", IncludeSections: []string{"types"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, dir := newTestGen(t, map[string]string{".dors.yaml": file}, tt.args)
+			if err := g.loadConfig(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			got := g.configFor(tt.pkgPath)
+			// Only the settings changed by the file, the overrides and the
+			// flags are compared.
+			got.Overrides = nil
+			got.ExcludePaths = nil
+			got.Lint = LintConfig{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configFor(%q) = %+v, want %+v", tt.pkgPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverrideUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data     string
+		wantPath string
+		wantErr  string
+	}{
+		{data: `{"path": "internal/...", "unexported": true}`, wantPath: "internal/..."},
+		{data: `{"path": "cmd"}`, wantPath: "cmd"},
+		{data: `{"unexported": true}`, wantErr: "override is missing the path key"},
+		{data: `{"path": 1}`, wantErr: "override path"},
+		{data: `{"path": "cmd", "overrides": []}`, wantErr: "override cmd: overrides cannot be nested"},
+		{data: `{"path": "cmd", "titel": "x"}`, wantErr: `override cmd: json: unknown field "titel"`},
+		{data: `{"path": "cmd", "unexported": "yes"}`, wantErr: "override cmd"},
+		{data: `[]`, wantErr: "cannot unmarshal array"},
+	}
+	for _, tt := range tests {
+		var o Override
+		err := o.UnmarshalJSON([]byte(tt.data))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("UnmarshalJSON(%s) error = %v, want %q", tt.data, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%s) error = %v", tt.data, err)
+			continue
+		}
+		if o.Path != tt.wantPath {
+			t.Errorf("UnmarshalJSON(%s) path = %q, want %q", tt.data, o.Path, tt.wantPath)
+		}
+	}
+}
+
+func TestChangedFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: `{}`},
+		{args: []string{"-t", "T"}, want: `{"title":"T"}`},
+		{args: []string{"--unexported=false", "-k"}, want: `{"skipSubPkgs":true,"unexported":false}`},
+		{args: []string{"-e", "a,b"}, want: `{"excludePaths":["a","b"]}`},
+		{args: []string{"--threshold", "80"}, want: `{"coverageThreshold":80}`},
+		{args: []string{"--enable", "period"}, want: `{"lint":{"enable":["period"],"disable":null}}`},
+	}
+	for _, tt := range tests {
+		var cfg Config
+		flags := newTestFlags(&cfg)
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		got, err := changedFlags(cfg, flags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("changedFlags(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	if got, err := changedFlags(Config{}, nil); got != nil || err != nil {
+		t.Errorf("changedFlags(nil) = %s, %v, want nil", got, err)
+	}
+}
+
+func TestFlagKey(t *testing.T) {
+	tests := map[string]string{
+		"title":            "title",
+		"skip-sub-pkgs":    "skipSubPkgs",
+		"include-sections": "includeSections",
+		"out-dir":          "outDir",
+		"threshold":        "coverageThreshold",
+		"enable":           "lint",
+		"disable":          "lint",
+	}
+	for name, want := range tests {
+		if got := flagKey(name); got != want {
+			t.Errorf("flagKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
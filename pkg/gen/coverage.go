@@ -0,0 +1,181 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/ulm0/dors/pkg/common"
+)
+
+// CoverageCount counts the exported symbols and the ones with a doc comment.
+type CoverageCount struct {
+	Documented int `json:"documented"`
+	Total      int `json:"total"`
+}
+
+// Percent returns the percentage of documented symbols, 100 when there are none.
+func (c CoverageCount) Percent() float64 {
+	if c.Total == 0 {
+		return 100
+	}
+	return float64(c.Documented) * 100 / float64(c.Total)
+}
+
+func (c *CoverageCount) add(documented bool) {
+	c.Total++
+	if documented {
+		c.Documented++
+	}
+}
+
+// UndocumentedSymbol is an exported symbol without a doc comment.
+type UndocumentedSymbol struct {
+	// Kind is one of common.Kinds.
+	Kind string `json:"kind"`
+	// ID identifies the symbol like common.Symbol, it's empty for the package.
+	ID string `json:"id,omitempty"`
+	// File declaring the symbol, relative to the root directory.
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// PackageCoverage is the documentation coverage of a package.
+type PackageCoverage struct {
+	// Path of the package relative to the root directory.
+	Path       string        `json:"path"`
+	ImportPath string        `json:"importPath"`
+	Total      CoverageCount `json:"total"`
+	// Kinds counts the symbols of each of common.Kinds found in the package.
+	Kinds        map[string]CoverageCount `json:"kinds"`
+	Undocumented []UndocumentedSymbol     `json:"undocumented"`
+}
+
+// CoverageReport is the documentation coverage of the packages of a project.
+type CoverageReport struct {
+	Packages []*PackageCoverage       `json:"packages"`
+	Total    CoverageCount            `json:"total"`
+	Kinds    map[string]CoverageCount `json:"kinds"`
+	// Threshold is the minimum percentage of documented symbols, see
+	// Config.CoverageThreshold.
+	Threshold float64 `json:"threshold"`
+	// Errors holds the packages that couldn't be loaded, they aren't counted.
+	Errors []*PackageError `json:"-"`
+}
+
+// Passed reports whether the total coverage reaches the threshold.
+func (r *CoverageReport) Passed() bool {
+	return r.Total.Percent() >= r.Threshold
+}
+
+// Coverage counts the exported symbols with and without a doc comment in the
+// packages under rootDir that Generate documents, with the same configuration.
+func (g *Gen) Coverage(ctx context.Context, rootDir string) (*CoverageReport, error) {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path: %w", err)
+	}
+	log.Info("Computing documentation coverage", "rootDir", rootDir)
+
+	run := &Gen{config: g.config.clone(), pinned: g.pinned, rootDir: rootDir}
+	if err := run.loadConfig(rootDir); err != nil {
+		return nil, err
+	}
+	if t := run.config.CoverageThreshold; t < 0 || t > 100 {
+		return nil, fmt.Errorf("invalid coverage threshold %v, expected a percentage between 0 and 100", t)
+	}
+
+	pkgs, pkgErrs, err := run.collectPkgs(ctx, rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("collecting packages: %w", err)
+	}
+
+	report := &CoverageReport{
+		Kinds:     make(map[string]CoverageCount),
+		Threshold: run.config.CoverageThreshold,
+		Errors:    pkgErrs,
+	}
+	for _, p := range pkgs {
+		if len(p.Package.Filenames) == 0 {
+			continue
+		}
+		pc := pkgCoverage(rootDir, p)
+		for kind, c := range pc.Kinds {
+			total := report.Kinds[kind]
+			total.Documented += c.Documented
+			total.Total += c.Total
+			report.Kinds[kind] = total
+		}
+		report.Total.Documented += pc.Total.Documented
+		report.Total.Total += pc.Total.Total
+		report.Packages = append(report.Packages, pc)
+	}
+	return report, nil
+}
+
+// pkgCoverage counts the exported symbols of pkg.
+func pkgCoverage(rootDir string, pkg *common.Pkg) *PackageCoverage {
+	pc := &PackageCoverage{
+		Path:         pkg.Path,
+		ImportPath:   pkg.Package.ImportPath,
+		Kinds:        make(map[string]CoverageCount),
+		Undocumented: []UndocumentedSymbol{},
+	}
+	for _, sym := range pkg.ExportedSymbols() {
+		documented := strings.TrimSpace(sym.Doc) != ""
+		c := pc.Kinds[sym.Kind]
+		c.add(documented)
+		pc.Kinds[sym.Kind] = c
+		pc.Total.add(documented)
+		if documented {
+			continue
+		}
+
+		u := UndocumentedSymbol{Kind: sym.Kind, ID: sym.ID}
+		if pos := pkg.FilesSet.Position(sym.Pos); pos.IsValid() {
+			u.File = pos.Filename
+			if rel, err := filepath.Rel(rootDir, pos.Filename); err == nil {
+				u.File = filepath.ToSlash(rel)
+			}
+			u.Line = pos.Line
+		}
+		pc.Undocumented = append(pc.Undocumented, u)
+	}
+	return pc
+}
+
+// WriteTable prints the report as a table with a row for each package and a
+// column for each kind of symbol, giving the documented and the total symbols.
+func (r *CoverageReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "PATH")
+	for _, kind := range common.Kinds {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(kind))
+	}
+	fmt.Fprint(tw, "\tCOVERAGE\n")
+
+	row := func(name string, kinds map[string]CoverageCount, total CoverageCount) {
+		fmt.Fprint(tw, name)
+		for _, kind := range common.Kinds {
+			if c, ok := kinds[kind]; ok && c.Total > 0 {
+				fmt.Fprintf(tw, "\t%d/%d", c.Documented, c.Total)
+			} else {
+				fmt.Fprint(tw, "\t-")
+			}
+		}
+		fmt.Fprintf(tw, "\t%.1f%%\n", total.Percent())
+	}
+	for _, p := range r.Packages {
+		name := p.Path
+		if name == "" {
+			name = "."
+		}
+		row(name, p.Kinds, p.Total)
+	}
+	row("TOTAL", r.Kinds, r.Total)
+	return tw.Flush()
+}
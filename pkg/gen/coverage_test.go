@@ -0,0 +1,186 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ulm0/dors/pkg/common"
+)
+
+// coverageFiles is a module whose package p documents some symbols of each
+// kind, with its package doc in its second file, and whose package q has no
+// package doc.
+var coverageFiles = map[string]string{
+	"go.mod": "module example.com/m\n\ngo 1.21\n",
+	"p/a.go": `package p
+
+// A is documented.
+const A = 1
+
+const B = 2
+
+// Values are documented as a group.
+var (
+	C = 3
+	D = 4 // D has a line comment.
+)
+
+func F() {}
+
+// T is a type.
+type T struct {
+	// X is documented.
+	X int
+	Y int
+}
+
+func (T) M() {}
+
+// I is an interface.
+type I interface {
+	// N is documented.
+	N()
+}
+`,
+	"p/doc.go": "// Package p is documented in its second file.\npackage p\n",
+	"q/q.go":   "package q\n\n// Q is documented.\nfunc Q() {}\n",
+}
+
+func TestCoverage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, coverageFiles)
+
+	report, err := New(Config{CoverageThreshold: 60}).Coverage(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) > 0 {
+		t.Fatalf("Coverage() errors = %v", report.Errors)
+	}
+	if len(report.Packages) != 2 {
+		t.Fatalf("Coverage() packages = %d, want 2", len(report.Packages))
+	}
+
+	p, q := report.Packages[0], report.Packages[1]
+	wantKinds := map[string]CoverageCount{
+		common.KindPackage: {Documented: 1, Total: 1},
+		common.KindConst:   {Documented: 1, Total: 2},
+		common.KindVar:     {Documented: 2, Total: 2},
+		common.KindFunc:    {Documented: 0, Total: 1},
+		common.KindType:    {Documented: 2, Total: 2},
+		common.KindField:   {Documented: 1, Total: 2},
+		common.KindMethod:  {Documented: 1, Total: 2},
+	}
+	if !reflect.DeepEqual(p.Kinds, wantKinds) {
+		t.Errorf("p kinds = %v, want %v", p.Kinds, wantKinds)
+	}
+	if want := (CoverageCount{Documented: 8, Total: 12}); p.Total != want {
+		t.Errorf("p total = %v, want %v", p.Total, want)
+	}
+	wantUndocumented := []UndocumentedSymbol{
+		{Kind: common.KindConst, ID: "B", File: "p/a.go", Line: 6},
+		{Kind: common.KindFunc, ID: "F", File: "p/a.go", Line: 14},
+		{Kind: common.KindField, ID: "T.Y", File: "p/a.go", Line: 20},
+		{Kind: common.KindMethod, ID: "T.M", File: "p/a.go", Line: 23},
+	}
+	if !reflect.DeepEqual(p.Undocumented, wantUndocumented) {
+		t.Errorf("p undocumented = %+v, want %+v", p.Undocumented, wantUndocumented)
+	}
+
+	// The missing package doc is reported at the package clause of the
+	// first file.
+	wantUndocumented = []UndocumentedSymbol{{Kind: common.KindPackage, File: "q/q.go", Line: 1}}
+	if !reflect.DeepEqual(q.Undocumented, wantUndocumented) {
+		t.Errorf("q undocumented = %+v, want %+v", q.Undocumented, wantUndocumented)
+	}
+
+	if want := (CoverageCount{Documented: 9, Total: 14}); report.Total != want {
+		t.Errorf("total = %v, want %v", report.Total, want)
+	}
+	if want := (CoverageCount{Documented: 1, Total: 2}); report.Kinds[common.KindPackage] != want {
+		t.Errorf("total packages = %v, want %v", report.Kinds[common.KindPackage], want)
+	}
+	if got, want := report.Total.Percent(), 900.0/14; got != want {
+		t.Errorf("total percent = %v, want %v", got, want)
+	}
+	if !report.Passed() {
+		t.Errorf("Passed() = false with %.1f%% and a threshold of %v%%", report.Total.Percent(), report.Threshold)
+	}
+}
+
+func TestCoverageThreshold(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, coverageFiles)
+
+	tests := []struct {
+		threshold float64
+		passed    bool
+		wantErr   bool
+	}{
+		{threshold: 0, passed: true},
+		{threshold: 64, passed: true},
+		{threshold: 64.3, passed: false},
+		{threshold: 100, passed: false},
+		{threshold: -1, wantErr: true},
+		{threshold: 101, wantErr: true},
+	}
+	for _, tt := range tests {
+		report, err := New(Config{CoverageThreshold: tt.threshold}).Coverage(context.Background(), dir)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Coverage() with threshold %v succeeded, want an error", tt.threshold)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Passed() != tt.passed {
+			t.Errorf("Passed() with threshold %v = %v, want %v", tt.threshold, report.Passed(), tt.passed)
+		}
+	}
+}
+
+func TestCoverageCountPercent(t *testing.T) {
+	tests := []struct {
+		count CoverageCount
+		want  float64
+	}{
+		{count: CoverageCount{}, want: 100},
+		{count: CoverageCount{Documented: 0, Total: 4}, want: 0},
+		{count: CoverageCount{Documented: 1, Total: 4}, want: 25},
+		{count: CoverageCount{Documented: 4, Total: 4}, want: 100},
+	}
+	for _, tt := range tests {
+		if got := tt.count.Percent(); got != tt.want {
+			t.Errorf("%+v.Percent() = %v, want %v", tt.count, got, tt.want)
+		}
+	}
+}
+
+// TestPackageClause checks that the package is positioned at the clause
+// holding its doc, which go/doc removes from the files.
+func TestPackageClause(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, coverageFiles)
+
+	pkgs, pkgErrs, err := loadPackages(context.Background(), dir, noUnexported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgErrs) > 0 {
+		t.Fatalf("loadPackages() errors = %v", pkgErrs)
+	}
+
+	want := map[string]string{"p": "doc.go:2", "q": "q.go:1"}
+	for _, p := range pkgs {
+		sym := p.ExportedSymbols()[0]
+		pos := p.FilesSet.Position(sym.Pos)
+		if got := fmt.Sprintf("%s:%d", filepath.Base(pos.Filename), pos.Line); sym.Kind != common.KindPackage || got != want[p.Path] {
+			t.Errorf("package %s is at %s, want %s", p.Path, got, want[p.Path])
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"sort"
+	"strings"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/markdown"
+	"github.com/ulm0/dors/pkg/gen/template"
+)
+
+// Notices of the deprecated packages and symbols.
+const (
+	// DeprecationAlert renders the deprecation as a GitHub "> [!WARNING]" alert.
+	DeprecationAlert = "alert"
+	// DeprecationQuote renders the deprecation as a blockquote, for renderers
+	// without alerts.
+	DeprecationQuote = "quote"
+	// DeprecationNone keeps the "Deprecated: " paragraph in the doc, without
+	// a notice.
+	DeprecationNone = "none"
+)
+
+// checkDeprecationNotice verifies the deprecation notice of the project and
+// of its overrides.
+func (g *Gen) checkDeprecationNotice() error {
+	if g.config.DeprecationNotice == "" {
+		g.config.DeprecationNotice = DeprecationAlert
+	}
+
+	check := func(notice string) error {
+		switch notice {
+		case DeprecationAlert, DeprecationQuote, DeprecationNone:
+			return nil
+		}
+		return fmt.Errorf("unknown deprecation notice %q, expected %s, %s or %s", notice, DeprecationAlert, DeprecationQuote, DeprecationNone)
+	}
+	if err := check(g.config.DeprecationNotice); err != nil {
+		return err
+	}
+	for _, o := range g.config.Overrides {
+		if err := check(g.configFor(o.Path).DeprecationNotice); err != nil {
+			return fmt.Errorf("override %s: %w", o.Path, err)
+		}
+	}
+	return nil
+}
+
+// deprecatedAPI is a deprecated package or symbol of the project.
+type deprecatedAPI struct {
+	pkg *common.Pkg
+	// recv and name identify the symbol like a doc link, name is empty for
+	// the package. The fields are identified by their struct type.
+	recv, name string
+	// field is the name of a deprecated field.
+	field string
+	// kind is one of common.Kinds.
+	kind string
+	// text of the "Deprecated: " paragraph.
+	text string
+}
+
+// deprecatedAPIs returns the deprecated package and symbols of pkg, in order
+// of appearance in the documentation.
+func deprecatedAPIs(pkg *common.Pkg) []deprecatedAPI {
+	var apis []deprecatedAPI
+	add := func(recv, name, field, kind, doc string) {
+		if text, ok := common.Deprecation(doc); ok {
+			apis = append(apis, deprecatedAPI{pkg: pkg, recv: recv, name: name, field: field, kind: kind, text: text})
+		}
+	}
+	addValues := func(values []*doc.Value, kind string) {
+		for _, v := range values {
+			if _, ok := common.Deprecation(v.Doc); ok {
+				for _, name := range v.Names {
+					add("", name, "", kind, v.Doc)
+				}
+				continue
+			}
+			for _, spec := range v.Decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range spec.Names {
+						add("", name.Name, "", kind, spec.Doc.Text())
+					}
+				}
+			}
+		}
+	}
+
+	p := pkg.Package
+	add("", "", "", common.KindPackage, p.Doc)
+	addValues(p.Consts, common.KindConst)
+	addValues(p.Vars, common.KindVar)
+	for _, f := range p.Funcs {
+		add("", f.Name, "", common.KindFunc, f.Doc)
+	}
+	for _, t := range p.Types {
+		add("", t.Name, "", common.KindType, t.Doc)
+		for _, field := range structFieldList(t.Decl) {
+			for _, name := range field.Names {
+				add("", t.Name, name.Name, common.KindField, field.Doc.Text())
+			}
+		}
+		addValues(t.Consts, common.KindConst)
+		addValues(t.Vars, common.KindVar)
+		for _, f := range t.Funcs {
+			add("", f.Name, "", common.KindFunc, f.Doc)
+		}
+		for _, m := range t.Methods {
+			// Methods promoted from embedded types are listed with their type.
+			if m.Level == 0 {
+				add(t.Name, m.Name, "", common.KindMethod, m.Doc)
+			}
+		}
+	}
+	return apis
+}
+
+// deprecatedReport returns the deprecated APIs of pkgs listed in the summary,
+// with links relative to the summary to the documentation of the ones that are
+// shown. The doc links of the replacements that can't be resolved are passed
+// to unresolved.
+func (g *Gen) deprecatedReport(pkgs []*common.Pkg, unresolved func(ref string)) []template.DeprecatedAPI {
+	apis := append([]deprecatedAPI(nil), g.deprecated...)
+	sort.SliceStable(apis, func(i, j int) bool {
+		return apis[i].pkg.Path < apis[j].pkg.Path
+	})
+
+	// The summary documents the root package, whose symbols link to its anchors.
+	root := &common.Pkg{}
+	for _, p := range pkgs {
+		if p.Path == "" {
+			root = p
+		}
+	}
+	var report []template.DeprecatedAPI
+	for _, api := range apis {
+		name := api.pkg.Path
+		if api.name != "" {
+			name = api.pkg.Package.Name + "." + common.SymbolID(api.recv, api.name)
+			if api.field != "" {
+				name += "." + api.field
+			}
+		} else if name == "" {
+			name = api.pkg.Package.ImportPath
+		}
+
+		var link string
+		if (api.pkg.Path != "" || api.name != "") && api.pkg.DocFile != "" && !g.configFor(api.pkg.Path).HideDeprecated {
+			if api.pkg.Path != "" {
+				link = common.RelLink(root, api.pkg)
+			}
+			if api.name != "" {
+				link += "#" + common.SymbolID(api.recv, api.name)
+			}
+		}
+		report = append(report, template.DeprecatedAPI{
+			Name:        name,
+			Kind:        api.kind,
+			Link:        link,
+			Replacement: g.replacementCell(root, api, unresolved),
+		})
+	}
+	return report
+}
+
+// replacementCell renders the "Deprecated: " paragraph of api as markdown for
+// a cell of the summary, resolving its doc links relative to root.
+func (g *Gen) replacementCell(root *common.Pkg, api deprecatedAPI, unresolved func(ref string)) string {
+	cfg := g.configFor(api.pkg.Path)
+	opts := g.markdownOptions(api.pkg, cfg, unresolved)
+	if !cfg.LegacyMarkdown {
+		opts = append(opts, markdown.OptDocLinkURL(g.docLinkURL(root, api.pkg, unresolved)))
+	}
+	b := &strings.Builder{}
+	markdown.ToMarkdown(b, api.text, opts...)
+	return strings.ReplaceAll(strings.Join(strings.Fields(b.String()), " "), "|", "\\|")
+}
+
+// hideDeprecated removes the deprecated symbols and struct fields from the
+// documentation of pkg.
+func hideDeprecated(pkg *common.Pkg) {
+	p := pkg.Package
+	p.Consts = visibleValues(p.Consts)
+	p.Vars = visibleValues(p.Vars)
+	p.Funcs = visibleFuncs(p.Funcs)
+
+	typs := p.Types[:0]
+	for _, t := range p.Types {
+		if isDeprecated(t.Doc) {
+			continue
+		}
+		t.Decl = withoutDeprecatedFields(t.Decl)
+		t.Consts = visibleValues(t.Consts)
+		t.Vars = visibleValues(t.Vars)
+		t.Funcs = visibleFuncs(t.Funcs)
+		t.Methods = visibleFuncs(t.Methods)
+		typs = append(typs, t)
+	}
+	p.Types = typs
+}
+
+// isDeprecated reports whether doc has a "Deprecated: " paragraph.
+func isDeprecated(doc string) bool {
+	_, ok := common.Deprecation(doc)
+	return ok
+}
+
+// visibleFuncs returns the funcs that aren't deprecated.
+func visibleFuncs(funcs []*doc.Func) []*doc.Func {
+	visible := funcs[:0]
+	for _, f := range funcs {
+		if !isDeprecated(f.Doc) {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// visibleValues returns the const and var groups that aren't deprecated,
+// without their deprecated specs.
+func visibleValues(values []*doc.Value) []*doc.Value {
+	visible := values[:0]
+	for _, v := range values {
+		if isDeprecated(v.Doc) {
+			continue
+		}
+
+		var specs []ast.Spec
+		var names []string
+		for _, spec := range v.Decl.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if ok && isDeprecated(vs.Doc.Text()) {
+				continue
+			}
+			specs = append(specs, spec)
+			if ok {
+				for _, name := range vs.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		if len(specs) < len(v.Decl.Specs) {
+			decl := *v.Decl
+			decl.Specs = specs
+			v.Decl = &decl
+			v.Names = names
+		}
+		visible = append(visible, v)
+	}
+	return visible
+}
+
+// structFieldList returns the fields of the struct type declared by decl,
+// nil when it isn't a struct.
+func structFieldList(decl *ast.GenDecl) []*ast.Field {
+	if len(decl.Specs) != 1 {
+		return nil
+	}
+	spec, ok := decl.Specs[0].(*ast.TypeSpec)
+	if !ok {
+		return nil
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok || st.Fields == nil {
+		return nil
+	}
+	return st.Fields.List
+}
+
+// withoutDeprecatedFields returns a copy of the struct type declaration decl
+// without its deprecated fields, or decl when it has none.
+func withoutDeprecatedFields(decl *ast.GenDecl) *ast.GenDecl {
+	fields := structFieldList(decl)
+	var visible []*ast.Field
+	for _, field := range fields {
+		if !isDeprecated(field.Doc.Text()) {
+			visible = append(visible, field)
+		}
+	}
+	if len(visible) == len(fields) {
+		return decl
+	}
+
+	spec := *decl.Specs[0].(*ast.TypeSpec)
+	st := *spec.Type.(*ast.StructType)
+	list := *st.Fields
+	list.List = visible
+	st.Fields = &list
+	spec.Type = &st
+	copied := *decl
+	copied.Specs = []ast.Spec{&spec}
+	return &copied
+}
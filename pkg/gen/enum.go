@@ -0,0 +1,396 @@
+package gen
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+)
+
+// constStrings returns the output of the String method of the constants whose
+// type has one, when it can be determined statically. It must run before
+// doc.NewFromFiles, which strips the function bodies from the syntax.
+//
+// The supported String methods are the ones generated by the stringer tool,
+// switches returning a string literal for each constant, and lookups in a
+// package level map or array literal. The if statements before them are
+// followed when their condition compares the receiver to constants, like the
+// bounds checks do.
+func constStrings(files []*ast.File, info *types.Info) map[types.Object]string {
+	// Initial values of the package level variables, for the lookups.
+	inits := make(map[types.Object]ast.Expr)
+	var methods []*ast.FuncDecl
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok != token.VAR {
+					continue
+				}
+				for _, s := range d.Specs {
+					spec := s.(*ast.ValueSpec)
+					if len(spec.Values) != len(spec.Names) {
+						continue
+					}
+					for i, name := range spec.Names {
+						if obj := info.Defs[name]; obj != nil {
+							inits[obj] = spec.Values[i]
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Name.Name == "String" && d.Recv != nil && d.Body != nil &&
+					d.Type.Params.NumFields() == 0 && d.Type.Results.NumFields() == 1 {
+					methods = append(methods, d)
+				}
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	// Constants grouped by their type.
+	consts := make(map[types.Type][]*types.Const)
+	for _, obj := range info.Defs {
+		if c, ok := obj.(*types.Const); ok && c.Parent() == c.Pkg().Scope() {
+			consts[c.Type()] = append(consts[c.Type()], c)
+		}
+	}
+
+	strs := make(map[types.Object]string)
+	for _, m := range methods {
+		recv := m.Recv.List[0]
+		if len(recv.Names) == 0 {
+			continue
+		}
+		obj := info.Defs[recv.Names[0]]
+		if obj == nil {
+			continue
+		}
+		typ := obj.Type()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		s := &stringer{info: info, inits: inits, recv: obj}
+		for _, c := range consts[typ] {
+			if str, ok := s.eval(m.Body, c.Val()); ok {
+				strs[c] = str
+			}
+		}
+	}
+	return strs
+}
+
+// stringer evaluates the String method of a type for a constant value.
+type stringer struct {
+	info  *types.Info
+	inits map[types.Object]ast.Expr
+	// recv is the receiver of the method.
+	recv types.Object
+}
+
+// eval returns the string the body of the String method returns for the
+// receiver value v.
+func (s *stringer) eval(body *ast.BlockStmt, v constant.Value) (string, bool) {
+	for _, stmt := range body.List {
+		switch st := stmt.(type) {
+		case *ast.AssignStmt:
+			// stringer shifts the receiver when the values don't start at 0:
+			// i -= 1
+			if st.Tok != token.SUB_ASSIGN || len(st.Lhs) != 1 || !s.isRecv(st.Lhs[0]) {
+				return "", false
+			}
+			offset := s.constant(st.Rhs[0])
+			if offset == nil || offset.Kind() != constant.Int {
+				return "", false
+			}
+			v = constant.BinaryOp(v, token.SUB, offset)
+		case *ast.IfStmt:
+			// Only the conditions comparing the receiver to constants, such
+			// as the bounds checks of stringer, can be evaluated.
+			if st.Init != nil {
+				return "", false
+			}
+			cond, ok := s.evalCond(st.Cond, v)
+			if !ok {
+				return "", false
+			}
+			switch {
+			case cond:
+				return s.eval(st.Body, v)
+			case st.Else != nil:
+				return s.eval(&ast.BlockStmt{List: []ast.Stmt{st.Else}}, v)
+			}
+		case *ast.SwitchStmt:
+			if st.Init != nil || st.Tag == nil || !s.isRecv(st.Tag) {
+				return "", false
+			}
+			// The statements after the switch run only when no case matches.
+			if str, matched, ok := s.evalSwitch(st, v); matched {
+				return str, ok
+			}
+		case *ast.ReturnStmt:
+			if len(st.Results) != 1 {
+				return "", false
+			}
+			return s.evalResult(st.Results[0], v)
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// evalCond evaluates a condition made of comparisons of the receiver value v
+// with constants, such as i < 0 || i >= T(len(_T_index)-1).
+func (s *stringer) evalCond(expr ast.Expr, v constant.Value) (result, ok bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.UnaryExpr:
+		if e.Op != token.NOT {
+			return false, false
+		}
+		x, ok := s.evalCond(e.X, v)
+		return !x, ok
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.LOR, token.LAND:
+			x, ok := s.evalCond(e.X, v)
+			if !ok {
+				return false, false
+			}
+			y, ok := s.evalCond(e.Y, v)
+			if !ok {
+				return false, false
+			}
+			if e.Op == token.LOR {
+				return x || y, true
+			}
+			return x && y, true
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			x, y := e.X, e.Y
+			op := e.Op
+			if s.isRecv(y) {
+				// Swap the operands, so that the receiver is on the left.
+				x, y = y, x
+				switch op {
+				case token.LSS:
+					op = token.GTR
+				case token.LEQ:
+					op = token.GEQ
+				case token.GTR:
+					op = token.LSS
+				case token.GEQ:
+					op = token.LEQ
+				}
+			}
+			c := s.constant(y)
+			if !s.isRecv(x) || c == nil || !comparableValues(c, v) {
+				return false, false
+			}
+			return constant.Compare(v, op, c), true
+		}
+	}
+	return false, false
+}
+
+// evalSwitch returns the string literal returned by the case matching v, and
+// whether a case other than the default one matches.
+func (s *stringer) evalSwitch(st *ast.SwitchStmt, v constant.Value) (str string, matched, ok bool) {
+	var dflt *ast.CaseClause
+	for _, stmt := range st.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			dflt = clause
+			continue
+		}
+		for _, expr := range clause.List {
+			c := s.constant(expr)
+			if c == nil {
+				// A case that can't be evaluated might match.
+				return "", true, false
+			}
+			if equal(c, v) {
+				str, ok := s.clauseResult(clause)
+				return str, true, ok
+			}
+		}
+	}
+	if dflt != nil {
+		str, ok := s.clauseResult(dflt)
+		return str, true, ok
+	}
+	return "", false, false
+}
+
+// clauseResult returns the string literal returned by a case clause.
+func (s *stringer) clauseResult(clause *ast.CaseClause) (string, bool) {
+	if len(clause.Body) != 1 {
+		return "", false
+	}
+	ret, ok := clause.Body[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", false
+	}
+	return s.stringConstant(ret.Results[0])
+}
+
+// evalResult evaluates the result of a return statement.
+func (s *stringer) evalResult(expr ast.Expr, v constant.Value) (string, bool) {
+	if str, ok := s.stringConstant(expr); ok {
+		return str, true
+	}
+
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.IndexExpr:
+		// names[i]
+		if !s.isRecv(e.Index) {
+			return "", false
+		}
+		elem, ok := s.lookup(e.X, v)
+		if !ok {
+			return "", false
+		}
+		return s.stringConstant(elem)
+	case *ast.SliceExpr:
+		// _T_name[_T_index[i]:_T_index[i+1]], as generated by stringer.
+		name, ok := s.stringConstant(e.X)
+		if !ok || e.Low == nil || e.High == nil {
+			return "", false
+		}
+		low, ok := s.indexBound(e.Low, v, 0)
+		if !ok {
+			return "", false
+		}
+		high, ok := s.indexBound(e.High, v, 1)
+		if !ok || low > high || high > len(name) {
+			return "", false
+		}
+		return name[low:high], true
+	}
+	return "", false
+}
+
+// indexBound evaluates a bound of the stringer slice expression, an index
+// into a package level array of offsets: _T_index[i] or _T_index[i+1].
+func (s *stringer) indexBound(expr ast.Expr, v constant.Value, delta int64) (int, bool) {
+	e, ok := ast.Unparen(expr).(*ast.IndexExpr)
+	if !ok {
+		return 0, false
+	}
+	index := ast.Unparen(e.Index)
+	if delta != 0 {
+		bin, ok := index.(*ast.BinaryExpr)
+		if !ok || bin.Op != token.ADD {
+			return 0, false
+		}
+		if d := s.constant(bin.Y); d == nil || d.Kind() != constant.Int {
+			return 0, false
+		} else if n, exact := constant.Int64Val(d); !exact || n != delta {
+			return 0, false
+		}
+		index = bin.X
+	}
+	if !s.isRecv(index) {
+		return 0, false
+	}
+
+	elem, ok := s.lookup(e.X, constant.BinaryOp(v, token.ADD, constant.MakeInt64(delta)))
+	if !ok {
+		return 0, false
+	}
+	c := s.constant(elem)
+	if c == nil || c.Kind() != constant.Int {
+		return 0, false
+	}
+	n, exact := constant.Int64Val(c)
+	return int(n), exact
+}
+
+// lookup returns the element for the key v of a package level variable
+// initialized with a map, array or slice literal.
+func (s *stringer) lookup(expr ast.Expr, v constant.Value) (ast.Expr, bool) {
+	id, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+	lit, ok := s.inits[s.info.Uses[id]].(*ast.CompositeLit)
+	if !ok {
+		return nil, false
+	}
+
+	_, isMap := s.info.TypeOf(lit).Underlying().(*types.Map)
+	next := constant.MakeInt64(0)
+	for _, elt := range lit.Elts {
+		key, value := next, elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			key = s.constant(kv.Key)
+			value = kv.Value
+			if key == nil {
+				return nil, false
+			}
+		} else if isMap {
+			return nil, false
+		}
+
+		if equal(key, v) {
+			return value, true
+		}
+		if !isMap {
+			next = constant.BinaryOp(key, token.ADD, constant.MakeInt64(1))
+		}
+	}
+	return nil, false
+}
+
+// isRecv reports whether expr is the receiver, possibly converted or dereferenced.
+func (s *stringer) isRecv(expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return s.info.Uses[e] == s.recv
+	case *ast.StarExpr:
+		return s.isRecv(e.X)
+	case *ast.CallExpr:
+		// Conversions such as int(i).
+		if len(e.Args) == 1 && s.info.Types[e.Fun].IsType() {
+			return s.isRecv(e.Args[0])
+		}
+	}
+	return false
+}
+
+// constant returns the value of a constant expression, or nil.
+func (s *stringer) constant(expr ast.Expr) constant.Value {
+	return s.info.Types[expr].Value
+}
+
+// stringConstant returns the value of a constant string expression.
+func (s *stringer) stringConstant(expr ast.Expr) (string, bool) {
+	c := s.constant(expr)
+	if c == nil || c.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(c), true
+}
+
+// comparableValues reports whether the constants x and y can be compared, both
+// being numbers or both being strings.
+func comparableValues(x, y constant.Value) bool {
+	numeric := func(v constant.Value) bool {
+		switch v.Kind() {
+		case constant.Int, constant.Float:
+			return true
+		}
+		return false
+	}
+	return numeric(x) && numeric(y) || x.Kind() == constant.String && y.Kind() == constant.String
+}
+
+// equal reports whether the constants x and y are equal, constants of
+// different kinds are never equal.
+func equal(x, y constant.Value) bool {
+	if (x.Kind() == constant.String) != (y.Kind() == constant.String) {
+		return false
+	}
+	return constant.Compare(x, token.EQL, y)
+}
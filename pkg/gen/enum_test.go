@@ -0,0 +1,241 @@
+package gen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"testing"
+)
+
+func TestConstStrings(t *testing.T) {
+	// consts declares the constants of the Color type, starting at 0.
+	const consts = "type Color int\n\nconst (\n\tRed Color = iota\n\tGreen\n\tBlue\n)\n"
+	tests := []struct {
+		name string
+		src  string
+		// want maps the names of the constants to their string, the
+		// constants missing from it can't be evaluated.
+		want map[string]string
+	}{
+		{
+			name: "switch",
+			src: consts + `
+func (c Color) String() string {
+	switch c {
+	case Red:
+		return "red"
+	case Green:
+		return "green"
+	}
+	return "unknown"
+}
+`,
+			want: map[string]string{"Red": "red", "Green": "green", "Blue": "unknown"},
+		},
+		{
+			name: "switch with default",
+			src: consts + `
+func (c *Color) String() string {
+	switch *c {
+	case Red, Green:
+		return "warm"
+	default:
+		return "cold"
+	}
+}
+`,
+			want: map[string]string{"Red": "warm", "Green": "warm", "Blue": "cold"},
+		},
+		{
+			name: "array index",
+			src: consts + `
+var colorNames = [...]string{"red", "green", "blue"}
+
+func (c Color) String() string {
+	if c < 0 || int(c) >= len(colorNames) {
+		return "unknown"
+	}
+	return colorNames[c]
+}
+`,
+			want: map[string]string{"Red": "red", "Green": "green", "Blue": "blue"},
+		},
+		{
+			name: "map lookup",
+			src: consts + `
+var colorNames = map[Color]string{Red: "red", Blue: "blue"}
+
+func (c Color) String() string {
+	return colorNames[c]
+}
+`,
+			want: map[string]string{"Red": "red", "Blue": "blue"},
+		},
+		{
+			name: "stringer",
+			src: consts + `
+const _Color_name = "RedGreenBlue"
+
+var _Color_index = [...]uint8{0, 3, 8, 12}
+
+func (i Color) String() string {
+	if i < 0 || i >= Color(len(_Color_index)-1) {
+		return "Color(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Color_name[_Color_index[i]:_Color_index[i+1]]
+}
+`,
+			want: map[string]string{"Red": "Red", "Green": "Green", "Blue": "Blue"},
+		},
+		{
+			name: "stringer with offset",
+			src: `type Color int
+
+const (
+	Red Color = iota + 1
+	Green
+	Blue
+)
+
+const _Color_name = "RedGreenBlue"
+
+var _Color_index = [...]uint8{0, 3, 8, 12}
+
+func (i Color) String() string {
+	i -= 1
+	if i < 0 || i >= Color(len(_Color_index)-1) {
+		return "Color(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	}
+	return _Color_name[_Color_index[i]:_Color_index[i+1]]
+}
+`,
+			want: map[string]string{"Red": "Red", "Green": "Green", "Blue": "Blue"},
+		},
+		{
+			name: "condition on a constant",
+			src: consts + `
+var colorNames = [...]string{"red", "green", "blue"}
+
+func (c Color) String() string {
+	if Blue == c {
+		return strconv.Itoa(int(c))
+	}
+	return colorNames[c]
+}
+`,
+			want: map[string]string{"Red": "red", "Green": "green"},
+		},
+		{
+			name: "condition not on the receiver",
+			src: consts + `
+var verbose bool
+
+var colorNames = [...]string{"red", "green", "blue"}
+
+func (c Color) String() string {
+	if verbose {
+		return "Color(" + strconv.Itoa(int(c)) + ")"
+	}
+	return colorNames[c]
+}
+`,
+		},
+		{
+			name: "condition on a variable",
+			src: consts + `
+var last = Green
+
+var colorNames = [...]string{"red", "green", "blue"}
+
+func (c Color) String() string {
+	if c > last {
+		return strconv.Itoa(int(c))
+	}
+	return colorNames[c]
+}
+`,
+		},
+		{
+			name: "condition with init",
+			src: consts + `
+var colorNames = [...]string{"red", "green", "blue"}
+
+func (c Color) String() string {
+	if n := int(c); n >= len(colorNames) {
+		return strconv.Itoa(n)
+	}
+	return colorNames[c]
+}
+`,
+		},
+		{
+			name: "slice length",
+			src: consts + `
+var colorNames = []string{"red", "green", "blue"}
+
+func (c Color) String() string {
+	if int(c) >= len(colorNames) {
+		return strconv.Itoa(int(c))
+	}
+	return colorNames[c]
+}
+`,
+		},
+		{
+			name: "switch without tag",
+			src: consts + `
+func (c Color) String() string {
+	switch {
+	case c == Red:
+		return "red"
+	}
+	return "other"
+}
+`,
+		},
+		{
+			name: "computed string",
+			src: consts + `
+func (c Color) String() string {
+	return strconv.Itoa(int(c))
+}
+`,
+		},
+	}
+
+	// The source importer type-checks strconv once for every case.
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parser.ParseFile(fset, "color.go", "package p\n\nimport \"strconv\"\n\nvar _ = strconv.Itoa\n\n"+tt.src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Defs:  make(map[*ast.Ident]types.Object),
+				Uses:  make(map[*ast.Ident]types.Object),
+				Types: make(map[ast.Expr]types.TypeAndValue),
+			}
+			conf := types.Config{Importer: imp}
+			if _, err := conf.Check("p", fset, []*ast.File{f}, info); err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string)
+			for obj, str := range constStrings([]*ast.File{f}, info) {
+				got[obj.Name()] = str
+			}
+			want := tt.want
+			if want == nil {
+				want = map[string]string{}
+			}
+			if !maps.Equal(got, want) {
+				t.Errorf("constStrings() = %v, want %v", got, want)
+			}
+		})
+	}
+}
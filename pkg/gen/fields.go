@@ -0,0 +1,51 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// addStructFields maps the struct fields declared in files to their syntax.
+func addStructFields(fields map[types.Object]*ast.Field, files []*ast.File, info *types.Info) {
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				names := field.Names
+				if len(names) == 0 {
+					// The embedded field is defined by the name of its type.
+					if id := embeddedName(field.Type); id != nil {
+						names = []*ast.Ident{id}
+					}
+				}
+				for _, name := range names {
+					if obj := info.Defs[name]; obj != nil {
+						fields[obj] = field
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// embeddedName returns the identifier of the type of an embedded field, such
+// as T in *pkg.T[int].
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedName(e.X)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/template"
+)
+
+// page returns the page of the HTML site documenting pkg, or the index page
+// listing the packages when pkg is nil.
+func (g *Gen) page(pkg *common.Pkg) *template.Page {
+	title := g.config.Title
+	if title == "" && len(g.pkgs) > 0 {
+		title = g.pkgs[0].Module
+	}
+	if title == "" {
+		title = "Documentation"
+	}
+
+	// Links are relative to the page, the index page is at the root of the site.
+	from := pkg
+	if from == nil {
+		from = &common.Pkg{}
+	}
+	var root string
+	if from.Path != "" {
+		root = strings.Repeat("../", strings.Count(from.Path, "/")+1)
+	}
+
+	return &template.Page{
+		Title: title,
+		Pkg:   pkg,
+		Root:  root,
+		Nav:   navItems(from, "", topLevelPkgs(g.pkgs)),
+	}
+}
+
+// navItems returns the sidebar tree of pkgs, whose parent is at parentPath,
+// with links relative to the page of from.
+func navItems(from *common.Pkg, parentPath string, pkgs []*common.Pkg) []*template.NavItem {
+	var items []*template.NavItem
+	for _, p := range pkgs {
+		link := common.RelLink(from, p)
+		if link == "" {
+			link = p.DocFile
+		}
+		items = append(items, &template.NavItem{
+			Name:     relPkgPath(parentPath, p.Path),
+			Link:     link,
+			Synopsis: p.Synopsis(),
+			Current:  p == from,
+			Children: navItems(from, p.Path, p.SubPkgs),
+		})
+	}
+	return items
+}
+
+// relPkgPath returns the path of a package relative to its parent in the tree.
+func relPkgPath(parentPath, pkgPath string) string {
+	if parentPath == "" {
+		return pkgPath
+	}
+	return strings.TrimPrefix(pkgPath, parentPath+"/")
+}
+
+// renderSiteIndex renders the index page of the HTML site listing the
+// packages, unless the root directory holds a package whose page takes its place.
+func (g *Gen) renderSiteIndex(allPackages []*common.Pkg) ([]docFile, error) {
+	for _, p := range allPackages {
+		if p.Path == "" && len(p.Package.Filenames) > 0 {
+			return nil, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := template.Execute(&buf, g.page(nil), g.config, template.Options{Custom: g.templateFS(g.config)}); err != nil {
+		return nil, fmt.Errorf("rendering site index: %w", err)
+	}
+	return []docFile{{Path: path.Join(g.outDir(), g.docFileName()), Content: buf.Bytes()}}, nil
+}
@@ -0,0 +1,51 @@
+package gen
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGenerateHTMLEscaping(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"m.go": `// Package m compares values with a < b.
+package m
+
+// Less reports whether a < b.
+func Less(a, b int) bool { return a < b }
+`,
+		"m_test.go": `package m
+
+import "fmt"
+
+func ExampleLess() {
+	fmt.Println("<less>", Less(1, 2))
+	// Output: <less> true
+}
+`,
+	})
+
+	result, err := New(Config{Format: FormatHTML, Title: `Docs <&> "m"`, IncludeSections: allSections}).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Generate() errors = %v", result.Errors)
+	}
+
+	page := readFile(t, dir, "site/index.html")
+	for _, want := range []string{
+		// Values printed by the templates are escaped.
+		"<title>m - Docs &lt;&amp;&gt; &#34;m&#34;</title>",
+		`<pre class="output">&lt;less&gt; true`,
+		// The doc comments and declarations are rendered as HTML.
+		"<p>Less reports whether a &lt; b.",
+		`<pre class="code"><span class="kw">func</span> Less(`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, page)
+		}
+	}
+}
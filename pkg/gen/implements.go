@@ -0,0 +1,221 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/template"
+	"golang.org/x/tools/go/packages"
+)
+
+// implementations relates the documented types of the project to the
+// interfaces they satisfy.
+type implementations struct {
+	// implements holds the interfaces satisfied by each concrete type.
+	implements map[*types.TypeName][]implementation
+	// implementedBy holds the concrete types satisfying each interface of the project.
+	implementedBy map[*types.TypeName][]implementation
+}
+
+// implementation is a type on the other side of the relation.
+type implementation struct {
+	obj *types.TypeName
+	// pointer is set when only the pointer to the concrete type satisfies the interface.
+	pointer bool
+}
+
+// newImplementations checks every documented concrete type of pkgs against the
+// documented interfaces of pkgs and the extra ones, with value and pointer receivers.
+func newImplementations(pkgs []*common.Pkg, extra []*types.TypeName) *implementations {
+	var concretes, ifaces []*types.TypeName
+	for _, p := range pkgs {
+		if p.Types == nil {
+			continue
+		}
+		for _, t := range p.Package.Types {
+			obj, ok := p.Types.Scope().Lookup(t.Name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				// Generic types are only related once instantiated.
+				continue
+			}
+			if _, ok := named.Underlying().(*types.Interface); ok {
+				if isMethodSet(obj) {
+					ifaces = append(ifaces, obj)
+				}
+				continue
+			}
+			concretes = append(concretes, obj)
+		}
+	}
+
+	impls := &implementations{
+		implements:    make(map[*types.TypeName][]implementation),
+		implementedBy: make(map[*types.TypeName][]implementation),
+	}
+	project := len(ifaces)
+	for i, iface := range append(ifaces, extra...) {
+		it := iface.Type().Underlying().(*types.Interface)
+		for _, c := range concretes {
+			var pointer bool
+			switch {
+			case types.Implements(c.Type(), it):
+			case types.Implements(types.NewPointer(c.Type()), it):
+				pointer = true
+			default:
+				continue
+			}
+			impls.implements[c] = append(impls.implements[c], implementation{obj: iface, pointer: pointer})
+			if i < project {
+				impls.implementedBy[iface] = append(impls.implementedBy[iface], implementation{obj: c, pointer: pointer})
+			}
+		}
+	}
+	return impls
+}
+
+// isMethodSet reports whether obj is an interface with methods that can be
+// used as a type, unlike the constraints holding type sets or any.
+func isMethodSet(obj *types.TypeName) bool {
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	return ok && iface.IsMethodSet() && iface.NumMethods() > 0
+}
+
+// loadInterfaces resolves the interfaces listed in the configuration, such as
+// "error" or "io.Reader". The packages imported by the project are reused, so
+// that their types are identical to the ones seen by the project; the rest are
+// loaded from rootDir. An interface that can't be resolved is an error.
+func (g *Gen) loadInterfaces(ctx context.Context, rootDir string, pkgs []*common.Pkg) ([]*types.TypeName, error) {
+	imported := make(map[string]*types.Package)
+	var walk func(p *types.Package)
+	walk = func(p *types.Package) {
+		if _, ok := imported[p.Path()]; ok {
+			return
+		}
+		imported[p.Path()] = p
+		for _, imp := range p.Imports() {
+			walk(imp)
+		}
+	}
+	for _, p := range pkgs {
+		if p.Types != nil {
+			walk(p.Types)
+		}
+	}
+
+	var missing []string
+	for _, name := range g.config.Interfaces {
+		if pkgPath, _, ok := splitQualified(name); ok && imported[pkgPath] == nil && !slices.Contains(missing, pkgPath) {
+			missing = append(missing, pkgPath)
+		}
+	}
+	if len(missing) > 0 {
+		// The packages are type-checked from source, like the project ones,
+		// since the export data of NeedTypes alone isn't available to every
+		// toolchain.
+		loaded, err := packages.Load(&packages.Config{
+			Context: ctx,
+			Mode:    packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+			Dir:     rootDir,
+		}, missing...)
+		if err != nil {
+			return nil, fmt.Errorf("loading the packages of the interfaces: %w", err)
+		}
+		for _, p := range loaded {
+			for _, e := range p.Errors {
+				log.Warn("Package of an interface contains errors", "package", p.PkgPath, "error", e)
+			}
+			if len(p.Errors) == 0 && p.Types != nil {
+				imported[p.PkgPath] = p.Types
+			}
+		}
+	}
+
+	var ifaces []*types.TypeName
+	for _, name := range g.config.Interfaces {
+		obj, err := lookupInterface(imported, name)
+		if err != nil {
+			return nil, fmt.Errorf("resolving interface %s: %w", name, err)
+		}
+		ifaces = append(ifaces, obj)
+	}
+	return ifaces, nil
+}
+
+// lookupInterface returns the interface called name, a predeclared name such as
+// "error" or a name qualified with its import path such as "io.Reader".
+func lookupInterface(pkgs map[string]*types.Package, name string) (*types.TypeName, error) {
+	var obj types.Object
+	if pkgPath, typeName, ok := splitQualified(name); ok {
+		p := pkgs[pkgPath]
+		if p == nil {
+			return nil, fmt.Errorf("package %s not found", pkgPath)
+		}
+		obj = p.Scope().Lookup(typeName)
+	} else {
+		obj = types.Universe.Lookup(name)
+	}
+
+	tn, ok := obj.(*types.TypeName)
+	if !ok || !isMethodSet(tn) {
+		return nil, fmt.Errorf("not an interface with methods")
+	}
+	return tn, nil
+}
+
+// splitQualified splits a name such as "encoding/json.Marshaler" into its import
+// path and type name.
+func splitQualified(name string) (pkgPath, typeName string, ok bool) {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 || i < strings.LastIndexByte(name, '/') {
+		return "", name, false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// usesImplementations reports whether the configuration or any of its
+// overrides renders the Implements sections.
+func (g *Gen) usesImplementations() bool {
+	if g.config.Implements {
+		return true
+	}
+	for _, o := range g.config.Overrides {
+		if g.configFor(o.Path).Implements {
+			return true
+		}
+	}
+	return false
+}
+
+// relations returns the function listing the types related to the types of pkg.
+func (g *Gen) relations(pkg *common.Pkg, rel map[*types.TypeName][]implementation) func(obj *types.TypeName) []template.Relation {
+	typeURL := g.typeURL(pkg)
+	qualifier := func(p *types.Package) string {
+		if p == pkg.Types {
+			return ""
+		}
+		return p.Name()
+	}
+
+	return func(obj *types.TypeName) []template.Relation {
+		var out []template.Relation
+		for _, impl := range rel[obj] {
+			out = append(out, template.Relation{
+				Name:    types.TypeString(impl.obj.Type(), qualifier),
+				URL:     typeURL(impl.obj),
+				Pointer: impl.pointer,
+			})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		return out
+	}
+}
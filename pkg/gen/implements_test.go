@@ -0,0 +1,45 @@
+package gen
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGenerateImplementsInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	// The package imports neither fmt nor io, their interfaces are loaded
+	// apart from the project.
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"m.go": `// Package m has a stringer.
+package m
+
+// Name is a stringer.
+type Name int
+
+// String returns the name.
+func (Name) String() string { return "name" }
+`,
+	})
+
+	cfg := Config{IncludeSections: allSections, Implements: true, Interfaces: []string{"error", "fmt.Stringer", "io.Reader", "io.Writer"}}
+	result, err := New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Generate() errors = %v", result.Errors)
+	}
+	want := "- [`fmt.Stringer`](https://pkg.go.dev/fmt#Stringer)"
+	if summary := readFile(t, dir, "DOCS.md"); !strings.Contains(summary, want) {
+		t.Errorf("DOCS.md doesn't contain %q:\n%s", want, summary)
+	}
+
+	for _, name := range []string{"io.Missing", "example.com/missing.Interface", "int"} {
+		cfg.Interfaces = []string{name}
+		if _, err := New(cfg).Generate(context.Background(), dir); err == nil {
+			t.Errorf("Generate() with interface %s succeeded, want an error", name)
+		}
+	}
+}
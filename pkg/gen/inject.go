@@ -0,0 +1,193 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/template"
+)
+
+// Markers delimiting the regions of the files updated by Config.Inject. The
+// start marker can name a section of the documentation to inject instead of
+// the whole documentation: <!-- dors:start section=types -->.
+const (
+	markerStart = "<!-- dors:start"
+	markerEnd   = "<!-- dors:end -->"
+)
+
+// region is the content between a start and an end marker.
+type region struct {
+	// section is the name given to the start marker, empty for the whole
+	// documentation.
+	section string
+	// start and end are the offsets of the content in the file.
+	start, end int
+}
+
+// findRegions returns the regions of content, the markers inside fenced code
+// blocks are ignored.
+func findRegions(content string) ([]region, error) {
+	var regions []region
+	var open *region
+	var fenced bool
+	offset := 0
+	for i, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		text := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(text, "```") || strings.HasPrefix(text, "~~~"):
+			fenced = !fenced
+		case fenced:
+		case strings.HasPrefix(text, markerStart):
+			if open != nil {
+				return nil, fmt.Errorf("line %d: region started before the end of the previous one", i+1)
+			}
+			section, err := parseMarker(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			open = &region{section: section, start: offset}
+		case text == markerEnd:
+			if open == nil {
+				return nil, fmt.Errorf("line %d: region ended without being started", i+1)
+			}
+			open.end = lineStart
+			regions = append(regions, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		return nil, errors.New("region started without an end marker")
+	}
+	return regions, nil
+}
+
+// parseMarker returns the section named by a start marker.
+func parseMarker(text string) (string, error) {
+	attrs, ok := strings.CutSuffix(strings.TrimPrefix(text, markerStart), "-->")
+	if !ok {
+		return "", fmt.Errorf("start marker %q isn't closed", text)
+	}
+
+	var section string
+	for _, attr := range strings.Fields(attrs) {
+		key, value, _ := strings.Cut(attr, "=")
+		value = strings.Trim(value, `"`)
+		if key != "section" || value == "" {
+			return "", fmt.Errorf("unknown marker attribute %q", attr)
+		}
+		section = value
+	}
+	return section, nil
+}
+
+// injectFile replaces the regions of the file at relPath, relative to the root
+// directory, with the documentation rendered by render for their section. It
+// returns nil when the file doesn't exist or has no regions, leaving it alone.
+func (g *Gen) injectFile(relPath string, render func(section string) ([]byte, error)) ([]byte, error) {
+	current, err := os.ReadFile(filepath.Join(g.rootDir, filepath.FromSlash(relPath)))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Warn("File to inject the documentation into not found. Skipping.", "path", relPath)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", relPath, err)
+	}
+
+	content := string(current)
+	regions, err := findRegions(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", relPath, err)
+	}
+	if len(regions) == 0 {
+		log.Warn("No dors:start and dors:end markers found. Skipping.", "path", relPath)
+		return nil, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, r := range regions {
+		doc, err := render(r.section)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", relPath, err)
+		}
+
+		b.WriteString(content[last:r.start])
+		b.WriteString("\n")
+		if text := strings.TrimSpace(string(doc)); text != "" {
+			b.WriteString(text + "\n\n")
+		}
+		last = r.end
+	}
+	b.WriteString(content[last:])
+	return []byte(b.String()), nil
+}
+
+// injectable reports whether the file at relPath, relative to the root
+// directory, has regions to inject the documentation into. The files whose
+// markers are invalid are, so that injectFile reports them.
+func (g *Gen) injectable(relPath string) bool {
+	content, err := os.ReadFile(filepath.Join(g.rootDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return false
+	}
+	regions, err := findRegions(string(content))
+	return err != nil || len(regions) > 0
+}
+
+// generatedLines reports for each line of content whether it holds generated
+// documentation: the lines between the markers of an injected file, or every
+// line of the other files.
+func generatedLines(content string, injected bool) []bool {
+	lines := strings.SplitAfter(content, "\n")
+	generated := make([]bool, len(lines))
+	if !injected {
+		for i := range generated {
+			generated[i] = true
+		}
+		return generated
+	}
+
+	// The markers were validated when the documentation was injected.
+	regions, _ := findRegions(content)
+	offset := 0
+	for i, line := range lines {
+		for _, r := range regions {
+			if offset >= r.start && offset < r.end {
+				generated[i] = true
+			}
+		}
+		offset += len(line)
+	}
+	return generated
+}
+
+// injectPkg injects the documentation of pkg into its file, see injectFile.
+func (g *Gen) injectPkg(pkg *common.Pkg, unresolved func(ref string)) ([]byte, error) {
+	return g.injectFile(g.docPath(pkg), func(section string) ([]byte, error) {
+		if section == "" {
+			return g.renderPkg(pkg, unresolved)
+		}
+		return g.renderSection(pkg, section, unresolved)
+	})
+}
+
+// renderSection renders a section of the documentation of pkg, one of
+// template.SectionNames.
+func (g *Gen) renderSection(pkg *common.Pkg, name string, unresolved func(ref string)) ([]byte, error) {
+	cfg := g.configFor(pkg.Path)
+	var buf bytes.Buffer
+	if err := template.Execute(&buf, &template.Section{Pkg: pkg, Name: name}, cfg, g.pkgOptions(pkg, cfg, unresolved)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,206 @@
+package gen
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []region
+		wantErr string
+	}{
+		{
+			name:    "no markers",
+			content: "# Title\n",
+		},
+		{
+			name:    "whole documentation",
+			content: "a\n<!-- dors:start -->\nold\n<!-- dors:end -->\nb\n",
+			want:    []region{{start: 22, end: 26}},
+		},
+		{
+			name:    "sections",
+			content: "<!-- dors:start section=types -->\n<!-- dors:end -->\n<!-- dors:start section=\"functions\" -->\n<!-- dors:end -->\n",
+			want:    []region{{section: "types", start: 34, end: 34}, {section: "functions", start: 92, end: 92}},
+		},
+		{
+			name:    "fenced markers",
+			content: "```\n<!-- dors:start -->\n```\n",
+		},
+		{
+			name:    "missing end",
+			content: "<!-- dors:start -->\n",
+			wantErr: "region started without an end marker",
+		},
+		{
+			name:    "missing start",
+			content: "<!-- dors:end -->\n",
+			wantErr: "line 1: region ended without being started",
+		},
+		{
+			name:    "nested",
+			content: "<!-- dors:start -->\n<!-- dors:start -->\n",
+			wantErr: "line 2: region started before the end of the previous one",
+		},
+		{
+			name:    "unknown attribute",
+			content: "<!-- dors:start name=types -->\n<!-- dors:end -->\n",
+			wantErr: `line 1: unknown marker attribute "name=types"`,
+		},
+		{
+			name:    "unclosed marker",
+			content: "<!-- dors:start\n<!-- dors:end -->\n",
+			wantErr: "isn't closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findRegions(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("findRegions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findRegions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// injectFiles is a module whose root README.md and sub/README.md have markers,
+// unlike other/README.md, and none has no README.md at all.
+var injectFiles = map[string]string{
+	"go.mod": "module example.com/m\n\ngo 1.21\n",
+	"m.go": `// Package m is the root package, see [Root], [Types], [sub.Sub] and [other.Other].
+package m
+
+// Root is declared in the root package.
+type Root struct{}
+
+// Types lists the types.
+func Types() []Root { return nil }
+`,
+	"README.md": `# Project
+
+<a id="start"></a>
+## Getting started
+
+Read [the start](#start) first.
+
+## func Types
+
+<!-- dors:start -->
+old documentation
+<!-- dors:end -->
+
+See [Root](#Root).
+`,
+	"sub/sub.go":      "// Package sub is injected.\npackage sub\n\n// Sub does things.\nfunc Sub() {}\n",
+	"sub/README.md":   "# Sub\n\n<!-- dors:start section=functions -->\n<!-- dors:end -->\n",
+	"other/other.go":  "// Package other has a README without markers.\npackage other\n\n// Other does things.\nfunc Other() {}\n",
+	"other/README.md": "# Other\n",
+	"none/none.go":    "// Package none has no README.\npackage none\n",
+}
+
+func TestGenerateInject(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, injectFiles)
+	cfg := Config{IncludeSections: allSections, Inject: true}
+
+	result, err := New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 || len(result.Unresolved) > 0 {
+		t.Fatalf("Generate() errors = %v, unresolved = %v", result.Errors, result.Unresolved)
+	}
+	if want := []string{"README.md", "sub/README.md"}; !reflect.DeepEqual(result.Files, want) {
+		t.Errorf("Generate() files = %q, want %q", result.Files, want)
+	}
+
+	readme := readFile(t, dir, "README.md")
+	before, _, _ := strings.Cut(injectFiles["README.md"], "old documentation")
+	_, after, _ := strings.Cut(injectFiles["README.md"], "<!-- dors:end -->")
+	if !strings.HasPrefix(readme, before) || !strings.HasSuffix(readme, "<!-- dors:end -->"+after) {
+		t.Errorf("README.md changed outside the markers:\n%s", readme)
+	}
+	for _, want := range []string{
+		"# Package `m`",
+		"[sub.Sub](sub/README.md#Sub)",
+		// The packages whose README.md isn't injected aren't linked.
+		"[other.Other](https://pkg.go.dev/example.com/m/other#Other)",
+		"* none: Package none has no README.",
+		"* other: Package other has a README without markers.",
+		"* [sub](sub/README.md): Package sub is injected.",
+	} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.md doesn't contain %q:\n%s", want, readme)
+		}
+	}
+	if strings.Contains(readme, "old documentation") {
+		t.Errorf("README.md still holds the replaced documentation:\n%s", readme)
+	}
+	if sub := readFile(t, dir, "sub/README.md"); !strings.Contains(sub, "## Functions") || strings.Contains(sub, "## Types") {
+		t.Errorf("sub/README.md doesn't hold only the functions section:\n%s", sub)
+	}
+	if other := readFile(t, dir, "other/README.md"); other != injectFiles["other/README.md"] {
+		t.Errorf("other/README.md changed:\n%s", other)
+	}
+
+	// Injecting the documentation again leaves the files as they are.
+	cfg.Check = true
+	result, err = New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range result.Stale {
+		t.Errorf("%s changed when injected again:\n%s", f.Path, f.Diff)
+	}
+}
+
+func TestGenerateInjectAnchors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, injectFiles)
+	cfg := Config{IncludeSections: allSections, Inject: true, Anchors: AnchorsGitHub}
+
+	result, err := New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Generate() errors = %v", result.Errors)
+	}
+
+	readme := readFile(t, dir, "README.md")
+	// The anchors and links written outside the markers are left alone.
+	for _, want := range []string{"<a id=\"start\"></a>\n## Getting started", "[the start](#start)", "See [Root](#Root)."} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.md doesn't contain %q:\n%s", want, readme)
+		}
+	}
+	// The headings outside the markers count for the unique IDs.
+	for _, want := range []string{"[Root](#type-root)", "[Types](#func-types-1)", "[sub.Sub](sub/README.md#func-sub)"} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.md doesn't contain %q:\n%s", want, readme)
+		}
+	}
+
+	cfg.Check = true
+	result, err = New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range result.Stale {
+		t.Errorf("%s changed when injected again:\n%s", f.Path, f.Diff)
+	}
+}
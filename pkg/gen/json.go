@@ -0,0 +1,97 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/template"
+)
+
+// Documentation formats.
+const (
+	// FormatMarkdown renders the documentation with the templates.
+	FormatMarkdown = "markdown"
+	// FormatJSON exports the documentation model as JSON documents following
+	// schema/dors.schema.json: one for each package, and one for the whole
+	// module in the root directory.
+	FormatJSON = "json"
+	// FormatHTML renders a static HTML site with the *.html.gotmpl templates:
+	// a page for each package, in a directory mirroring the package tree.
+	FormatHTML = "html"
+)
+
+// checkFormat verifies the documentation format, which can't be changed by
+// the overrides since the summary covers every package.
+func (g *Gen) checkFormat() error {
+	switch g.config.Format {
+	case "":
+		g.config.Format = FormatMarkdown
+	case FormatMarkdown, FormatJSON, FormatHTML:
+	default:
+		return fmt.Errorf("unknown format %q, expected %s, %s or %s", g.config.Format, FormatMarkdown, FormatJSON, FormatHTML)
+	}
+
+	for _, o := range g.config.Overrides {
+		if f := g.configFor(o.Path).Format; f != g.config.Format {
+			return fmt.Errorf("override %s: the format cannot be overridden", o.Path)
+		}
+	}
+	return nil
+}
+
+// renderJSON encodes a JSON document.
+func (g *Gen) renderJSON(doc *template.Document) ([]byte, error) {
+	doc.SchemaVersion = template.SchemaVersion
+
+	// Signatures and markdown are kept readable, the documents aren't meant
+	// to be embedded in HTML.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encoding JSON documentation: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSummaryJSON renders the JSON document of the whole module.
+func (g *Gen) renderSummaryJSON(allPackages []*common.Pkg) (docFile, error) {
+	doc := &template.Document{Packages: []*template.Package{}}
+	var unresolved []string
+	for _, pkg := range allPackages {
+		if len(pkg.Package.Filenames) == 0 {
+			continue
+		}
+		if doc.Module == "" {
+			doc.Module = pkg.Module
+		}
+
+		// The root package has no document of its own, the unresolved links
+		// of the others are reported by their documents.
+		report := func(string) {}
+		if pkg.Path == "" {
+			report = func(ref string) {
+				if !slices.Contains(unresolved, ref) {
+					unresolved = append(unresolved, ref)
+				}
+			}
+		}
+		cfg := g.configFor(pkg.Path)
+		opts := template.Options{
+			Markdown: g.markdownOptions(pkg, cfg, report),
+			TypeURL:  g.typeURL(pkg),
+		}
+		doc.Packages = append(doc.Packages, template.NewPackage(pkg, cfg.SkipExamples, opts))
+	}
+
+	content, err := g.renderJSON(doc)
+	if err != nil {
+		return docFile{}, err
+	}
+	return docFile{Path: path.Join(g.outDir(), g.docFileName()), Content: content, Unresolved: unresolved}, nil
+}
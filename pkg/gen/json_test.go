@@ -0,0 +1,245 @@
+package gen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestGenerateJSON covers the documents of the packages and of the whole
+// module, whose references don't depend on the location of the document.
+func TestGenerateJSON(t *testing.T) {
+	t.Run("links", func(t *testing.T) {
+		checkGoldenDocs(t, "links", Config{Format: FormatJSON})
+	})
+	t.Run("generics", func(t *testing.T) {
+		checkGoldenDocs(t, "generics", Config{Format: FormatJSON, OutDir: "json"})
+	})
+}
+
+// TestGenerateJSONRootPackage covers the root package, documented by the
+// document of the module, which reports its unresolved links.
+func TestGenerateJSONRootPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n",
+		"m.go":       "// Package m refers to [Missing].\npackage m\n",
+		"sub/sub.go": "// Package sub refers to [Gone].\npackage sub\n",
+	})
+
+	result, err := New(Config{Format: FormatJSON}).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UnresolvedLink{{File: "DOCS.json", Link: "[Missing]"}, {File: "sub/DOCS.json", Link: "[Gone]"}}
+	slices.SortFunc(result.Unresolved, func(a, b UnresolvedLink) int { return strings.Compare(a.File, b.File) })
+	if !reflect.DeepEqual(result.Unresolved, want) {
+		t.Errorf("Generate() unresolved = %v, want %v", result.Unresolved, want)
+	}
+
+	var doc struct {
+		Packages []struct{ ImportPath string }
+	}
+	readJSON(t, filepath.Join(dir, "DOCS.json"), &doc)
+	var got []string
+	for _, p := range doc.Packages {
+		got = append(got, p.ImportPath)
+	}
+	if want := []string{"example.com/m", "example.com/m/sub"}; !slices.Equal(got, want) {
+		t.Errorf("DOCS.json packages = %q, want %q", got, want)
+	}
+}
+
+// TestJSONSchema validates the testdata JSON documents against the published
+// schema.
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]any
+	readJSON(t, filepath.Join("..", "..", "schema", "dors.schema.json"), &schema)
+
+	var docs []string
+	err := filepath.WalkDir("testdata", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Name() == "DOCS.json" {
+			docs = append(docs, p)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) == 0 {
+		t.Fatal("no JSON documents in testdata")
+	}
+
+	for _, p := range docs {
+		var doc any
+		readJSON(t, p, &doc)
+		v := &schemaValidator{root: schema}
+		v.validate(schema, doc, "")
+		for _, err := range v.errs {
+			t.Errorf("%s: %s", p, err)
+		}
+	}
+}
+
+func TestJSONSchemaValidator(t *testing.T) {
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"kind"},
+		"properties": map[string]any{
+			"kind":  map[string]any{"enum": []any{"const", "var"}},
+			"names": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/name"}},
+		},
+		"$defs": map[string]any{
+			"name": map[string]any{"type": "string"},
+		},
+	}
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{doc: `{"kind": "const", "names": ["A"]}`},
+		{doc: `{"names": []}`, want: []string{`: missing property "kind"`}},
+		{doc: `{"kind": "func"}`, want: []string{`/kind: "func" is not one of [const var]`}},
+		{doc: `{"kind": "var", "names": [1]}`, want: []string{"/names/0: 1 is not of type string"}},
+		{doc: `[]`, want: []string{": [] is not of type object"}},
+	}
+	for _, tt := range tests {
+		var doc any
+		if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+			t.Fatal(err)
+		}
+		v := &schemaValidator{root: schema}
+		v.validate(schema, doc, "")
+		if !reflect.DeepEqual(v.errs, tt.want) {
+			t.Errorf("validate(%s) = %q, want %q", tt.doc, v.errs, tt.want)
+		}
+	}
+}
+
+func readJSON(t *testing.T, name string, v any) {
+	t.Helper()
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+// schemaValidator validates JSON values against the subset of JSON Schema
+// used by schema/dors.schema.json: type, const, enum, required, properties,
+// items, allOf and references to $defs.
+type schemaValidator struct {
+	root map[string]any
+	errs []string
+}
+
+func (v *schemaValidator) errorf(ptr, format string, args ...any) {
+	v.errs = append(v.errs, ptr+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) validate(schema map[string]any, value any, ptr string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, ok := v.resolve(ref)
+		if !ok {
+			v.errorf(ptr, "unknown reference %s", ref)
+			return
+		}
+		v.validate(def, value, ptr)
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, s := range all {
+			v.validate(s.(map[string]any), value, ptr)
+		}
+	}
+	if typ, ok := schema["type"].(string); ok && !hasType(value, typ) {
+		v.errorf(ptr, "%s is not of type %s", compact(value), typ)
+		return
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(value, c) {
+		v.errorf(ptr, "%s is not %s", compact(value), compact(c))
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		v.errorf(ptr, "%s is not one of %v", compact(value), enum)
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := value[name.(string)]; !ok {
+					v.errorf(ptr, "missing property %q", name)
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, prop := range props {
+			if field, ok := value[name]; ok {
+				v.validate(prop.(map[string]any), field, ptr+"/"+name)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s/%d", ptr, i))
+			}
+		}
+	}
+}
+
+// resolve returns the schema of a "#/$defs/name" reference.
+func (v *schemaValidator) resolve(ref string) (map[string]any, bool) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, false
+	}
+	defs, _ := v.root["$defs"].(map[string]any)
+	def, ok := defs[name].(map[string]any)
+	return def, ok
+}
+
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	}
+	return false
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compact(value any) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
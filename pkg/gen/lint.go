@@ -0,0 +1,518 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc/comment"
+	"go/token"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/markdown"
+)
+
+// Rules of the doc comment linter.
+const (
+	// RuleDocName reports the doc comments of exported symbols that don't
+	// start with their name.
+	RuleDocName = "doc-name"
+	// RulePackageComment reports the packages without a package comment, and
+	// the package comments that don't start with "Package name".
+	RulePackageComment = "package-comment"
+	// RulePeriod reports the doc comments whose last sentence doesn't end
+	// with a period, ignoring a trailing "Default:" line.
+	RulePeriod = "period"
+	// RuleDocLink reports the doc links that don't point to a known symbol.
+	RuleDocLink = "doc-link"
+	// RuleHeading reports the lines that look like headings but aren't
+	// rendered as such, like "## Title" or "#Title".
+	RuleHeading = "heading"
+	// RuleCodeIndent reports the code blocks indented with both tabs and spaces.
+	RuleCodeIndent = "code-indent"
+)
+
+// LintRules lists the rules of the doc comment linter.
+var LintRules = []string{RuleDocName, RulePackageComment, RulePeriod, RuleDocLink, RuleHeading, RuleCodeIndent}
+
+// LintConfig selects the rules of the doc comment linter.
+type LintConfig struct {
+	// Enable lists the rules to run, all of them when empty.
+	Enable []string `json:"enable"`
+	// Disable lists the rules not to run.
+	Disable []string `json:"disable"`
+}
+
+// enabled reports whether rule is run.
+func (c LintConfig) enabled(rule string) bool {
+	if len(c.Enable) > 0 && !slices.Contains(c.Enable, rule) {
+		return false
+	}
+	return !slices.Contains(c.Disable, rule)
+}
+
+// check verifies the names of the rules.
+func (c LintConfig) check() error {
+	for _, rule := range append(slices.Clone(c.Enable), c.Disable...) {
+		if !slices.Contains(LintRules, rule) {
+			return fmt.Errorf("unknown lint rule %q, expected one of %s", rule, strings.Join(LintRules, ", "))
+		}
+	}
+	return nil
+}
+
+// LintIssue is a problem found in a doc comment.
+type LintIssue struct {
+	// File holding the comment, relative to the root directory.
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Rule is one of LintRules.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// LintReport holds the problems found in the doc comments of a project.
+type LintReport struct {
+	Issues []LintIssue `json:"issues"`
+	// Errors holds the packages that couldn't be loaded, they aren't linted.
+	Errors []*PackageError `json:"-"`
+}
+
+// WriteText prints the issues in the file:line:column format understood by
+// editors, one per line.
+func (r *LintReport) WriteText(w io.Writer) error {
+	for _, issue := range r.Issues {
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s (%s)\n", issue.File, issue.Line, issue.Column, issue.Message, issue.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGitHub prints the issues as GitHub Actions workflow commands, which
+// annotate the lines of the pull requests.
+func (r *LintReport) WriteGitHub(w io.Writer) error {
+	data := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	property := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	for _, issue := range r.Issues {
+		if _, err := fmt.Fprintf(w, "::warning file=%s,line=%d,col=%d,title=%s::%s\n", property.Replace(issue.File), issue.Line, issue.Column, property.Replace("dors "+issue.Rule), data.Replace(issue.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lint checks the doc comments of the packages under rootDir that Generate
+// documents, with the rules enabled by Config.Lint.
+func (g *Gen) Lint(ctx context.Context, rootDir string) (*LintReport, error) {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path: %w", err)
+	}
+	log.Info("Linting doc comments", "rootDir", rootDir)
+
+	run := &Gen{config: g.config.clone(), pinned: g.pinned, rootDir: rootDir}
+	if err := run.loadConfig(rootDir); err != nil {
+		return nil, err
+	}
+	if err := run.config.Lint.check(); err != nil {
+		return nil, err
+	}
+	for _, o := range run.config.Overrides {
+		if err := run.configFor(o.Path).Lint.check(); err != nil {
+			return nil, fmt.Errorf("override %s: %w", o.Path, err)
+		}
+	}
+
+	pkgs, pkgErrs, err := run.collectPkgs(ctx, rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("collecting packages: %w", err)
+	}
+	run.pkgs = pkgs
+	run.pkgNames = pkgNames(pkgs)
+	run.index = common.NewIndex(pkgs)
+
+	report := &LintReport{Issues: []LintIssue{}, Errors: pkgErrs}
+	for _, p := range pkgs {
+		if len(p.Package.Filenames) == 0 {
+			continue
+		}
+		l := &linter{g: run, pkg: p, cfg: run.configFor(p.Path), rootDir: rootDir}
+		l.lintPkg()
+		report.Issues = append(report.Issues, l.issues...)
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// docComments maps the files, declarations, specs and fields of files to
+// their doc comment.
+func docComments(files []*ast.File) map[ast.Node]*ast.CommentGroup {
+	docs := make(map[ast.Node]*ast.CommentGroup)
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			var doc *ast.CommentGroup
+			switch n := n.(type) {
+			case *ast.File:
+				doc = n.Doc
+			case *ast.FuncDecl:
+				if n.Doc != nil {
+					docs[n] = n.Doc
+				}
+				// The comments of the body aren't doc comments.
+				return false
+			case *ast.GenDecl:
+				doc = n.Doc
+			case *ast.TypeSpec:
+				doc = n.Doc
+			case *ast.ValueSpec:
+				doc = n.Doc
+			case *ast.Field:
+				doc = n.Doc
+			}
+			if doc != nil {
+				docs[n] = doc
+			}
+			return true
+		})
+	}
+	return docs
+}
+
+// linter checks the doc comments of a package.
+type linter struct {
+	g       *Gen
+	pkg     *common.Pkg
+	cfg     Config
+	rootDir string
+	issues  []LintIssue
+}
+
+// report adds an issue at pos when rule is enabled.
+func (l *linter) report(pos token.Pos, rule, format string, args ...any) {
+	if !l.cfg.Lint.enabled(rule) {
+		return
+	}
+	position := l.pkg.FilesSet.Position(pos)
+	file := position.Filename
+	if rel, err := filepath.Rel(l.rootDir, file); err == nil {
+		file = filepath.ToSlash(rel)
+	}
+	l.issues = append(l.issues, LintIssue{
+		File:    file,
+		Line:    position.Line,
+		Column:  position.Column,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lintPkg checks the package comment and the doc comments of the exported
+// symbols of the package.
+func (l *linter) lintPkg() {
+	p := l.pkg
+	if p.Package.Doc == "" && len(p.Files) > 0 {
+		l.report(p.Files[0].Package, RulePackageComment, "package %s has no package comment", p.Package.Name)
+	}
+
+	for _, f := range p.Files {
+		if ast.IsGenerated(f) {
+			continue
+		}
+		if doc := p.Docs[f]; doc != nil {
+			if p.Package.Name != "main" && !strings.HasPrefix(doc.Text(), "Package "+p.Package.Name+" ") {
+				l.report(doc.Pos(), RulePackageComment, "package comment should be of the form %q", "Package "+p.Package.Name+" ...")
+			}
+			l.lintDoc(doc, "")
+		}
+
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				kind := "function"
+				if d.Recv != nil {
+					kind = "method"
+					if recv := recvIdent(d.Recv); recv == nil || !recv.IsExported() {
+						continue
+					}
+				}
+				if d.Name.IsExported() {
+					l.lintDoc(p.Docs[d], kind, d.Name.Name)
+				}
+			case *ast.GenDecl:
+				l.lintGenDecl(d)
+			}
+		}
+	}
+}
+
+// lintGenDecl checks the doc comments of the exported types, constants and
+// variables of decl, and of the fields and methods of the exported types.
+func (l *linter) lintGenDecl(decl *ast.GenDecl) {
+	docs := l.pkg.Docs
+	grouped := decl.Lparen.IsValid()
+	if grouped {
+		// The doc of a group describes the group, not the first symbol.
+		l.lintDoc(docs[decl], "")
+	}
+
+	for _, spec := range decl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			if !s.Name.IsExported() {
+				continue
+			}
+			doc := docs[s]
+			if doc == nil && !grouped {
+				doc = docs[decl]
+			}
+			l.lintDoc(doc, "type", s.Name.Name)
+
+			var members *ast.FieldList
+			switch t := s.Type.(type) {
+			case *ast.StructType:
+				members = t.Fields
+			case *ast.InterfaceType:
+				members = t.Methods
+			}
+			if members == nil {
+				continue
+			}
+			for _, field := range members.List {
+				if slices.ContainsFunc(field.Names, (*ast.Ident).IsExported) {
+					l.lintDoc(docs[field], "")
+				}
+			}
+		case *ast.ValueSpec:
+			if !slices.ContainsFunc(s.Names, (*ast.Ident).IsExported) {
+				continue
+			}
+			doc := docs[s]
+			if doc == nil && !grouped {
+				doc = docs[decl]
+			}
+			kind := "const"
+			if decl.Tok == token.VAR {
+				kind = "var"
+			}
+			names := make([]string, len(s.Names))
+			for i, name := range s.Names {
+				names[i] = name.Name
+			}
+			l.lintDoc(doc, kind, names...)
+		}
+	}
+}
+
+// recvIdent returns the name of the type of a receiver.
+func recvIdent(recv *ast.FieldList) *ast.Ident {
+	if len(recv.List) == 0 {
+		return nil
+	}
+	return embeddedName(recv.List[0].Type)
+}
+
+// commentLine is a line of a comment group, without the comment markers.
+type commentLine struct {
+	text string
+	pos  token.Pos
+}
+
+// commentLines splits a comment group into lines, removing the comment
+// markers like ast.CommentGroup.Text does.
+func commentLines(doc *ast.CommentGroup) []commentLine {
+	var lines []commentLine
+	for _, c := range doc.List {
+		if strings.HasPrefix(c.Text, "//") {
+			text := strings.TrimPrefix(c.Text[2:], " ")
+			lines = append(lines, commentLine{text: text, pos: c.Slash})
+			continue
+		}
+		text := strings.TrimSuffix(c.Text[2:], "*/")
+		offset := 2
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, commentLine{text: line, pos: c.Slash + token.Pos(offset)})
+			offset += len(line) + 1
+		}
+	}
+	return lines
+}
+
+// lintDoc checks a doc comment. The doc-name rule is checked when names are
+// given, the doc must start with one of them.
+func (l *linter) lintDoc(doc *ast.CommentGroup, kind string, names ...string) {
+	if doc == nil {
+		return
+	}
+	text := doc.Text()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	lines := commentLines(doc)
+
+	if len(names) > 0 && !strings.HasPrefix(text, "Deprecated: ") && !startsWithName(text, names) {
+		l.report(doc.Pos(), RuleDocName, "comment on exported %s %s should be of the form %q", kind, names[0], names[0]+" ...")
+	}
+
+	parsed := new(comment.Parser).Parse(text)
+	l.lintPeriod(parsed, lines)
+	l.lintHeadings(parsed, lines)
+	l.lintCodeIndent(lines)
+
+	// The links are resolved like in the documentation.
+	cfg := l.cfg
+	cfg.LegacyMarkdown = false
+	var unresolved []string
+	opts := l.g.markdownOptions(l.pkg, cfg, func(ref string) {
+		if !slices.Contains(unresolved, ref) {
+			unresolved = append(unresolved, ref)
+		}
+	})
+	markdown.ToMarkdown(io.Discard, text, opts...)
+	for _, ref := range unresolved {
+		pos := doc.Pos()
+		for _, line := range lines {
+			if strings.Contains(line.text, ref) {
+				pos = line.pos
+				break
+			}
+		}
+		l.report(pos, RuleDocLink, "doc link %s doesn't point to a known symbol", ref)
+	}
+}
+
+// startsWithName reports whether text starts with one of names, optionally
+// preceded by an article like "A Reader ...".
+func startsWithName(text string, names []string) bool {
+	words := strings.Fields(text)
+	if len(words) > 1 && (words[0] == "A" || words[0] == "An" || words[0] == "The") {
+		if slices.Contains(names, words[1]) {
+			return true
+		}
+	}
+	return len(words) > 0 && slices.Contains(names, words[0])
+}
+
+// lintPeriod checks that the last paragraph of a doc ends with a punctuation mark.
+func (l *linter) lintPeriod(doc *comment.Doc, lines []commentLine) {
+	if len(doc.Content) == 0 {
+		return
+	}
+	para, ok := doc.Content[len(doc.Content)-1].(*comment.Paragraph)
+	if !ok || len(para.Text) == 0 {
+		return
+	}
+	var last string
+	switch t := para.Text[len(para.Text)-1].(type) {
+	case comment.Plain:
+		last = string(t)
+	case comment.Italic:
+		last = string(t)
+	default:
+		// Paragraphs ending with a URL are left alone.
+		return
+	}
+	// A trailing "Default:" line gives the default value of a field, like in
+	// the field tables, it isn't a sentence.
+	i := strings.LastIndex(last, "\n")
+	trailingDefault := (i >= 0 || len(para.Text) == 1) && isDefaultLine(last[i+1:])
+	if trailingDefault {
+		last = last[:i+1]
+	}
+	last = strings.TrimRight(strings.TrimSpace(last), `)"'`)
+	if last == "" || strings.ContainsAny(last[len(last)-1:], ".!?:") {
+		return
+	}
+
+	pos := lines[0].pos
+	for i := len(lines) - 1; i >= 0; i-- {
+		text := lines[i].text
+		if strings.TrimSpace(text) != "" && !(trailingDefault && isDefaultLine(text)) {
+			pos = lines[i].pos
+			break
+		}
+	}
+	l.report(pos, RulePeriod, "comment should end with a period")
+}
+
+// isDefaultLine reports whether line gives the default value of a field.
+func isDefaultLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "Default:")
+}
+
+// headingLikeRx matches the lines meant to be headings.
+var headingLikeRx = regexp.MustCompile(`^#+(\s|\p{Lu})`)
+
+// lintHeadings checks that the lines starting with a "#" are rendered as headings.
+func (l *linter) lintHeadings(doc *comment.Doc, lines []commentLine) {
+	headings := make(map[string]bool)
+	for _, block := range doc.Content {
+		if h, ok := block.(*comment.Heading); ok {
+			var text strings.Builder
+			for _, t := range h.Text {
+				if plain, ok := t.(comment.Plain); ok {
+					text.WriteString(string(plain))
+				}
+			}
+			headings[text.String()] = true
+		}
+	}
+
+	for _, line := range lines {
+		if !headingLikeRx.MatchString(line.text) {
+			continue
+		}
+		if title, ok := strings.CutPrefix(line.text, "# "); ok && headings[strings.TrimSpace(title)] {
+			continue
+		}
+		l.report(line.pos, RuleHeading, "%q is not rendered as a heading, headings are a single \"# Title\" line surrounded by blank lines", line.text)
+	}
+}
+
+// listItemRx matches the first line of a list item.
+var listItemRx = regexp.MustCompile(`^([-*+•]|\d+[.)])\s`)
+
+// lintCodeIndent checks that the lines of the code blocks are indented with
+// either tabs or spaces.
+func (l *linter) lintCodeIndent(lines []commentLine) {
+	var block []commentLine
+	check := func() {
+		defer func() { block = nil }()
+		if len(block) == 0 || listItemRx.MatchString(strings.TrimSpace(block[0].text)) {
+			return
+		}
+		indent := block[0].text[0]
+		for _, line := range block[1:] {
+			if line.text[0] != indent {
+				l.report(line.pos, RuleCodeIndent, "code block is indented with both tabs and spaces")
+				return
+			}
+		}
+	}
+
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line.text) == "":
+			// Blank lines don't end the code blocks.
+		case line.text[0] == ' ' || line.text[0] == '\t':
+			block = append(block, line)
+		default:
+			check()
+		}
+	}
+	check()
+}
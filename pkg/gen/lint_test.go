@@ -0,0 +1,169 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		// src is the source of the package, following a package comment
+		// except for the package-comment cases.
+		src string
+		// want holds the issues of the rule as "line: message".
+		want []string
+	}{
+		{
+			name: "doc starts with name",
+			rule: RuleDocName,
+			src:  "// F does things.\nfunc F() {}\n",
+		},
+		{
+			name: "doc starts with article",
+			rule: RuleDocName,
+			src:  "// A Reader reads.\ntype Reader struct{}\n\n// An Error fails.\ntype Error struct{}\n\n// The Config configures.\ntype Config struct{}\n",
+		},
+		{
+			name: "article before another word",
+			rule: RuleDocName,
+			src:  "// A reader reads.\ntype Reader struct{}\n",
+			want: []string{`4: comment on exported type Reader should be of the form "Reader ..."`},
+		},
+		{
+			name: "deprecated doc",
+			rule: RuleDocName,
+			src:  "// Deprecated: Use G instead.\nfunc F() {}\n\n// G does things.\nfunc G() {}\n",
+		},
+		{
+			name: "doc without name",
+			rule: RuleDocName,
+			src:  "// Does things.\nfunc F() {}\n\ntype T struct{}\n\n// Does things.\nfunc (T) M() {}\n",
+			want: []string{
+				`4: comment on exported function F should be of the form "F ..."`,
+				`9: comment on exported method M should be of the form "M ..."`,
+			},
+		},
+		{
+			name: "grouped values",
+			rule: RuleDocName,
+			src:  "// Values.\nconst (\n\t// A is a.\n\tA = 1\n\t// Is b.\n\tB = 2\n)\n",
+			want: []string{`8: comment on exported const B should be of the form "B ..."`},
+		},
+		{
+			name: "missing package comment",
+			rule: RulePackageComment,
+			src:  "package p\n",
+			want: []string{"1: package p has no package comment"},
+		},
+		{
+			name: "package comment form",
+			rule: RulePackageComment,
+			src:  "// This package does things.\npackage p\n",
+			want: []string{`1: package comment should be of the form "Package p ..."`},
+		},
+		{
+			name: "command comment",
+			rule: RulePackageComment,
+			src:  "// Command p does things.\npackage main\n",
+		},
+		{
+			name: "period",
+			rule: RulePeriod,
+			src:  "// F does things!\nfunc F() {}\n\n// G is documented at:\n// https://example.com\nfunc G() {}\n\n// H does things\n// on two lines\nfunc H() {}\n",
+			want: []string{"12: comment should end with a period"},
+		},
+		{
+			name: "period before default line",
+			rule: RulePeriod,
+			src:  "// Config configures.\ntype Config struct {\n\t// Port to listen on.\n\t// Default: 8080\n\tPort int\n\t// Host to listen on\n\t// Default: localhost\n\tHost string\n\t// Default: 10\n\tSize int\n}\n",
+			want: []string{"9: comment should end with a period"},
+		},
+		{
+			name: "doc link",
+			rule: RuleDocLink,
+			src:  "// F calls [G] and [Missing].\nfunc F() {}\n\n// G does things.\nfunc G() {}\n",
+			want: []string{"4: doc link [Missing] doesn't point to a known symbol"},
+		},
+		{
+			name: "index expressions",
+			rule: RuleDocLink,
+			src:  "// F returns s[i], m[key] and buf[n] for the [G] of x[F].\nfunc F() {}\n\n// G does things.\nfunc G() {}\n",
+		},
+		{
+			name: "heading",
+			rule: RuleHeading,
+			src:  "// F does things.\n//\n// # Usage\n//\n// Call F.\n//\n// ## Options\n//\n// #Notes\nfunc F() {}\n",
+			want: []string{
+				`10: "## Options" is not rendered as a heading, headings are a single "# Title" line surrounded by blank lines`,
+				`12: "#Notes" is not rendered as a heading, headings are a single "# Title" line surrounded by blank lines`,
+			},
+		},
+		{
+			name: "code indent",
+			rule: RuleCodeIndent,
+			src:  "// F does things:\n//\n//\tF()\n//\tF()\n//\n// Or:\n//\n//\tF()\n//    F()\nfunc F() {}\n",
+			want: []string{"12: code block is indented with both tabs and spaces"},
+		},
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{"go.mod": "module example.com/m\n\ngo 1.21\n"}
+	for i, tt := range tests {
+		src := tt.src
+		if tt.rule != RulePackageComment {
+			src = "// Package p is linted.\npackage p\n\n" + src
+		}
+		files[fmt.Sprintf("p%d/p.go", i)] = src
+	}
+	writeFiles(t, dir, files)
+
+	report, err := New(Config{}).Lint(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) > 0 {
+		t.Fatalf("Lint() errors = %v", report.Errors)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := fmt.Sprintf("p%d/p.go", i)
+			var got []string
+			for _, issue := range report.Issues {
+				if issue.File == file && issue.Rule == tt.rule {
+					got = append(got, fmt.Sprintf("%d: %s", issue.Line, issue.Message))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintConfigEnabled(t *testing.T) {
+	tests := []struct {
+		cfg  LintConfig
+		want []string
+	}{
+		{cfg: LintConfig{}, want: LintRules},
+		{cfg: LintConfig{Enable: []string{RulePeriod, RuleHeading}}, want: []string{RulePeriod, RuleHeading}},
+		{cfg: LintConfig{Disable: []string{RuleDocName}}, want: LintRules[1:]},
+		{cfg: LintConfig{Enable: []string{RulePeriod, RuleHeading}, Disable: []string{RulePeriod}}, want: []string{RuleHeading}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, rule := range LintRules {
+			if tt.cfg.enabled(rule) {
+				got = append(got, rule)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v enables %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
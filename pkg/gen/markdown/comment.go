@@ -19,6 +19,8 @@ import (
 
 	"github.com/charmbracelet/log"
 	// for HTMLEscape
+
+	"github.com/ulm0/dors/pkg/common"
 )
 
 // ToMarkdown converts comment text to formatted Markdown.
@@ -39,19 +41,37 @@ import (
 // URLs in the comment text are converted into links; if the URL also appears
 // in the words map, the link is taken from the map (if the corresponding map
 // value is the empty string, the URL is not converted into a link).
+//
+// Unless disabled with OptUseStdlib, the comment is parsed with the Go 1.19
+// doc comment syntax of go/doc/comment instead, which adds lists, "# Heading"
+// lines, link definitions and doc links to symbols.
 func ToMarkdown(w io.Writer, text string, opts ...Option) {
-	var o options
+	o := options{useStdlib: true}
 	for _, f := range opts {
 		f(&o)
 	}
+	if o.skipDeprecated {
+		text = common.WithoutDeprecation(text)
+	}
 
 	if o.useStdlib {
-		parser := comment.Parser{Words: o.words}
-		printer := comment.Printer{HeadingLevel: 2}
-		_, err := w.Write(printer.Markdown(parser.Parse(text)))
-		if err != nil {
-			log.Error(err)
+		parser := comment.Parser{
+			Words:         o.words,
+			LookupPackage: o.lookupPackage,
+			LookupSym:     o.lookupSym,
+		}
+		printer := comment.Printer{
+			HeadingLevel:   2,
+			DocLinkBaseURL: docLinkBaseURL,
+			DocLinkURL:     o.docLinkURL,
+			// Heading IDs are printed as {#id}, which most markdown renderers don't support.
+			HeadingID: func(*comment.Heading) string { return "" },
 		}
+		doc := parser.Parse(text)
+		if o.unresolved != nil {
+			reportUnresolved(doc, o.unresolved)
+		}
+		writeMarkdown(w, &printer, doc, o.noDiffs)
 		return
 	}
 
@@ -81,6 +101,37 @@ func ToMarkdown(w io.Writer, text string, opts ...Option) {
 	}
 }
 
+// ToHTML converts comment text to HTML with the go/doc/comment parser, the
+// legacy parser only renders markdown. Headings are <h2> elements, like the
+// markdown headings.
+func ToHTML(w io.Writer, text string, opts ...Option) {
+	var o options
+	for _, f := range opts {
+		f(&o)
+	}
+	if o.skipDeprecated {
+		text = common.WithoutDeprecation(text)
+	}
+
+	parser := comment.Parser{
+		Words:         o.words,
+		LookupPackage: o.lookupPackage,
+		LookupSym:     o.lookupSym,
+	}
+	printer := comment.Printer{
+		HeadingLevel:   2,
+		DocLinkBaseURL: docLinkBaseURL,
+		DocLinkURL:     o.docLinkURL,
+	}
+	doc := parser.Parse(text)
+	if o.unresolved != nil {
+		reportUnresolved(doc, o.unresolved)
+	}
+	if _, err := w.Write(printer.HTML(doc)); err != nil {
+		log.Errorf("Error writing HTML: %v", err)
+	}
+}
+
 // Option is option type for ToMarkdown
 type Option func(*options)
 
@@ -97,14 +148,159 @@ func OptNoDiff(noDiffs bool) Option {
 	return func(o *options) { o.noDiffs = noDiffs }
 }
 
+// OptUseStdlib selects the go/doc/comment parser, enabled by default.
+// When disabled, the legacy parser is used.
 func OptUseStdlib(useStdlib bool) Option {
 	return func(o *options) { o.useStdlib = useStdlib }
 }
 
+// OptLookup sets the functions resolving the doc links to the symbols of the
+// package and of the packages it refers to, see comment.Parser for their semantics.
+func OptLookup(lookupPackage func(name string) (importPath string, ok bool), lookupSym func(recv, name string) bool) Option {
+	return func(o *options) {
+		o.lookupPackage = lookupPackage
+		o.lookupSym = lookupSym
+	}
+}
+
+// OptDocLinkURL sets the function returning the URL of a doc link,
+// by default links point to pkg.go.dev.
+func OptDocLinkURL(docLinkURL func(link *comment.DocLink) string) Option {
+	return func(o *options) { o.docLinkURL = docLinkURL }
+}
+
+// OptUnresolved sets a function called with every name in brackets that
+// couldn't be resolved into a doc link, brackets included.
+func OptUnresolved(unresolved func(ref string)) Option {
+	return func(o *options) { o.unresolved = unresolved }
+}
+
+// OptSkipDeprecated leaves out the "Deprecated: " paragraph, when the
+// deprecation is rendered as a notice of its own.
+func OptSkipDeprecated(skipDeprecated bool) Option {
+	return func(o *options) { o.skipDeprecated = skipDeprecated }
+}
+
 type options struct {
-	words     map[string]string
-	noDiffs   bool
-	useStdlib bool // Use standard library comments parsers introduced in Go 1.19.
+	words          map[string]string
+	noDiffs        bool
+	useStdlib      bool // Use standard library comments parsers introduced in Go 1.19.
+	skipDeprecated bool
+
+	lookupPackage func(name string) (importPath string, ok bool)
+	lookupSym     func(recv, name string) bool
+	docLinkURL    func(link *comment.DocLink) string
+	unresolved    func(ref string)
+}
+
+// docRefRx matches the text of a doc link: [Name], [Name.Method], [pkg.Name],
+// [pkg.Name.Method] or any of them starting with a '*'.
+var docRefRx = regexp.MustCompile(`\[\*?` + identRx + `(\.` + identRx + `){0,2}\]`)
+
+// reportRefs calls unresolved for the doc link candidates of text. Like in
+// go/doc/comment, the brackets must be preceded and followed by punctuation,
+// spaces or the start or end of the text, so s[i] isn't a link.
+func reportRefs(text string, unresolved func(ref string)) {
+	for _, loc := range docRefRx.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if loc[0] > 0 && !isLinkBoundary(before) || loc[1] < len(text) && !isLinkBoundary(after) {
+			continue
+		}
+		unresolved(text[loc[0]:loc[1]])
+	}
+}
+
+// isLinkBoundary reports whether r can precede or follow a doc link.
+func isLinkBoundary(r rune) bool {
+	return unicode.IsPunct(r) || r == ' ' || r == '\t' || r == '\n'
+}
+
+// reportUnresolved calls unresolved for the references left as plain text by the parser.
+func reportUnresolved(doc *comment.Doc, unresolved func(ref string)) {
+	var walkText func(text []comment.Text)
+	walkText = func(text []comment.Text) {
+		for _, t := range text {
+			switch t := t.(type) {
+			case comment.Plain:
+				reportRefs(string(t), unresolved)
+			case comment.Italic:
+				reportRefs(string(t), unresolved)
+			case *comment.Link:
+				walkText(t.Text)
+			}
+		}
+	}
+
+	var walkBlocks func(blocks []comment.Block)
+	walkBlocks = func(blocks []comment.Block) {
+		for _, b := range blocks {
+			switch b := b.(type) {
+			case *comment.Paragraph:
+				walkText(b.Text)
+			case *comment.Heading:
+				walkText(b.Text)
+			case *comment.List:
+				for _, item := range b.Items {
+					walkBlocks(item.Content)
+				}
+			}
+		}
+	}
+
+	walkBlocks(doc.Content)
+}
+
+// docLinkBaseURL is the base of the URLs doc links point to.
+const docLinkBaseURL = "https://pkg.go.dev"
+
+// writeMarkdown prints a parsed comment, code blocks are printed as fenced
+// blocks instead of the indented blocks of comment.Printer.
+func writeMarkdown(w io.Writer, printer *comment.Printer, doc *comment.Doc, noDiffs bool) {
+	for i, b := range doc.Content {
+		if i > 0 {
+			fmt.Fprint(w, "\n")
+		}
+
+		var err error
+		switch b := b.(type) {
+		case *comment.Code:
+			_, err = fmt.Fprintf(w, "```%s\n%s```\n", codeLang(b.Text, noDiffs), b.Text)
+		default:
+			_, err = w.Write(printer.Markdown(&comment.Doc{Content: []comment.Block{b}, Links: doc.Links}))
+		}
+		if err != nil {
+			log.Error(err)
+			return
+		}
+	}
+}
+
+// codeLang returns the language of a code block, it is a diff when all the
+// lines start with a '+', '-' or ' ' and at least one is a '+' or a '-'.
+func codeLang(text string, skipDiffs bool) string {
+	if skipDiffs {
+		return "go"
+	}
+
+	anyDiff := false
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '+', '-':
+			anyDiff = true
+		case ' ':
+		default:
+			return "go"
+		}
+	}
+
+	if anyDiff {
+		return "diff"
+	}
+	return "go"
 }
 
 const (
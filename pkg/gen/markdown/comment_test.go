@@ -0,0 +1,116 @@
+package markdown
+
+import (
+	"flag"
+	"fmt"
+	"go/doc/comment"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// docLinkOptions resolve the doc links of the testdata comments as if they
+// were written in a package declaring Config and Gen.Generate, whose project
+// holds the package example.com/m/other.
+var docLinkOptions = []Option{
+	OptLookup(func(name string) (string, bool) {
+		if name == "other" {
+			return "example.com/m/other", true
+		}
+		return "", false
+	}, func(recv, name string) bool {
+		return recv == "" && name == "Config" || recv == "Gen" && name == "Generate"
+	}),
+	OptDocLinkURL(func(link *comment.DocLink) string {
+		id := link.Name
+		if link.Recv != "" {
+			id = link.Recv + "." + id
+		}
+		switch link.ImportPath {
+		case "":
+			return "#" + id
+		case "example.com/m/other":
+			return "../other/DOCS.md#" + id
+		}
+		return link.DefaultURL(docLinkBaseURL)
+	}),
+}
+
+// goldenOptions holds the options of the testdata comments other than the
+// doc link ones, by name.
+var goldenOptions = map[string][]Option{
+	"deprecated": {OptSkipDeprecated(true)},
+}
+
+// TestToMarkdownGolden renders the testdata/*.txt comments with the
+// go/doc/comment parser into the *.md files, listing the unresolved doc links
+// in the *.unresolved files, and with the legacy parser into the *.legacy.md
+// files. Run with -update to rewrite them.
+func TestToMarkdownGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			text, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b strings.Builder
+			var refs strings.Builder
+			opts := append(slices.Clone(docLinkOptions), goldenOptions[name]...)
+			opts = append(opts, OptUnresolved(func(ref string) {
+				fmt.Fprintln(&refs, ref)
+			}))
+			ToMarkdown(&b, string(text), opts...)
+			checkGolden(t, filepath.Join("testdata", name+".md"), b.String())
+			checkGolden(t, filepath.Join("testdata", name+".unresolved"), refs.String())
+
+			b.Reset()
+			ToMarkdown(&b, string(text), append([]Option{OptUseStdlib(false)}, goldenOptions[name]...)...)
+			checkGolden(t, filepath.Join("testdata", name+".legacy.md"), b.String())
+		})
+	}
+}
+
+// checkGolden compares got with the content of the golden file, or writes it
+// to the file with -update.
+func checkGolden(t *testing.T, golden, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run with -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("output doesn't match %s:\n--- got\n%s\n--- want\n%s", golden, got, want)
+	}
+}
+
+func TestToMarkdownUnresolved(t *testing.T) {
+	var refs []string
+	opts := append(slices.Clone(docLinkOptions), OptUnresolved(func(ref string) {
+		refs = append(refs, ref)
+	}))
+	ToMarkdown(&strings.Builder{}, "See [Config], [Missing], [other.Missing] and [*Gen.Missing], not s[i] or [Index]es.\n\n  - In [List] items.\n", opts...)
+
+	// The symbols of other packages can't be looked up, they're left to the
+	// doc link URL function.
+	want := []string{"[Missing]", "[*Gen.Missing]", "[List]"}
+	if !slices.Equal(refs, want) {
+		t.Errorf("unresolved references = %q, want %q", refs, want)
+	}
+}
@@ -0,0 +1,97 @@
+package gen
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulm0/dors/pkg/common"
+)
+
+// defaultSiteDir is the directory of the HTML site when Config.OutDir isn't set.
+const defaultSiteDir = "site"
+
+// checkOutput verifies the name of the documentation files, and that the
+// single file and the injection are only requested in markdown.
+func (g *Gen) checkOutput() error {
+	if g.config.SingleFile && g.config.Format != FormatMarkdown {
+		return fmt.Errorf("single file documentation is only available in %s", FormatMarkdown)
+	}
+	if g.config.Inject && (g.config.Format != FormatMarkdown || g.config.SingleFile) {
+		return fmt.Errorf("injecting the documentation is only available in %s, with a file for each package", FormatMarkdown)
+	}
+
+	name := g.config.OutputName
+	if name == "" {
+		return nil
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("output name %q must be a file name without directories", name)
+	}
+	if strings.HasSuffix(name, ".go") {
+		return fmt.Errorf("output name %q would overwrite Go sources", name)
+	}
+	return nil
+}
+
+// docFileName returns the name of the documentation files.
+func (g *Gen) docFileName() string {
+	if g.config.OutputName != "" {
+		return g.config.OutputName
+	}
+	switch g.config.Format {
+	case FormatJSON:
+		return "DOCS.json"
+	case FormatHTML:
+		return "index.html"
+	}
+	if g.config.Inject {
+		return "README.md"
+	}
+	return "DOCS.md"
+}
+
+// outDir returns the directory the documentation is written to relative to
+// the root directory, or an empty string when it's written next to the sources.
+func (g *Gen) outDir() string {
+	out := g.config.OutDir
+	if out == "" && g.config.Format == FormatHTML {
+		out = defaultSiteDir
+	}
+	if out == "" {
+		return ""
+	}
+
+	rel, err := filepath.Rel(g.rootDir, g.resolvePath(out))
+	if err != nil {
+		return filepath.ToSlash(out)
+	}
+	if rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// docPath returns the path of the documentation of pkg relative to the root
+// directory.
+func (g *Gen) docPath(pkg *common.Pkg) string {
+	return path.Join(g.outDir(), pkg.Path, g.docFileName())
+}
+
+// sourceDir returns the directory of the sources of pkg relative to its
+// documentation, or an empty string when they're in the same directory. The
+// links to the declarations in the sources go through it.
+func (g *Gen) sourceDir(pkg *common.Pkg) string {
+	docDir := path.Join(g.outDir(), pkg.Path)
+	if g.config.SingleFile {
+		docDir = g.outDir()
+	}
+
+	dir := filepath.Join(g.rootDir, filepath.FromSlash(pkg.Path))
+	rel, err := filepath.Rel(filepath.Join(g.rootDir, filepath.FromSlash(docDir)), dir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
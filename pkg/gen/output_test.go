@@ -0,0 +1,112 @@
+package gen
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ulm0/dors/pkg/common"
+)
+
+// testRoot is the root directory of the tests that don't touch the files.
+var testRoot = filepath.FromSlash("/work/project")
+
+func TestOutDir(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{cfg: Config{}, want: ""},
+		{cfg: Config{Format: FormatJSON}, want: ""},
+		{cfg: Config{Format: FormatHTML}, want: "site"},
+		{cfg: Config{Format: FormatHTML, OutDir: "public"}, want: "public"},
+		{cfg: Config{OutDir: "docs"}, want: "docs"},
+		{cfg: Config{OutDir: "./docs/api/"}, want: "docs/api"},
+		{cfg: Config{OutDir: "."}, want: ""},
+		{cfg: Config{OutDir: filepath.Join(testRoot, "docs")}, want: "docs"},
+		{cfg: Config{OutDir: filepath.FromSlash("/work/docs")}, want: "../docs"},
+		{cfg: Config{OutDir: "../docs"}, want: "../docs"},
+	}
+	for _, tt := range tests {
+		g := &Gen{config: tt.cfg, rootDir: testRoot}
+		if got := g.outDir(); got != tt.want {
+			t.Errorf("outDir() with out dir %q and format %q = %q, want %q", tt.cfg.OutDir, tt.cfg.Format, got, tt.want)
+		}
+	}
+}
+
+func TestSourceDir(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		pkgPath string
+		want    string
+	}{
+		{cfg: Config{}, pkgPath: "", want: ""},
+		{cfg: Config{}, pkgPath: "a/b", want: ""},
+		{cfg: Config{OutDir: "docs"}, pkgPath: "", want: ".."},
+		{cfg: Config{OutDir: "docs"}, pkgPath: "a/b", want: "../../../a/b"},
+		{cfg: Config{OutDir: "docs/api"}, pkgPath: "a", want: "../../../a"},
+		{cfg: Config{OutDir: "../docs"}, pkgPath: "a", want: "../../project/a"},
+		{cfg: Config{Format: FormatHTML}, pkgPath: "a", want: "../../a"},
+		// The single file is at the top of the output directory.
+		{cfg: Config{SingleFile: true}, pkgPath: "a/b", want: "a/b"},
+		{cfg: Config{SingleFile: true, OutDir: "docs"}, pkgPath: "a/b", want: "../a/b"},
+		{cfg: Config{SingleFile: true, OutDir: "docs"}, pkgPath: "", want: ".."},
+	}
+	for _, tt := range tests {
+		g := &Gen{config: tt.cfg, rootDir: testRoot}
+		if got := g.sourceDir(&common.Pkg{Path: tt.pkgPath}); got != tt.want {
+			t.Errorf("sourceDir(%q) with out dir %q, format %q and single file %v = %q, want %q", tt.pkgPath, tt.cfg.OutDir, tt.cfg.Format, tt.cfg.SingleFile, got, tt.want)
+		}
+	}
+}
+
+func TestDocFileName(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{cfg: Config{}, want: "DOCS.md"},
+		{cfg: Config{Format: FormatJSON}, want: "DOCS.json"},
+		{cfg: Config{Format: FormatHTML}, want: "index.html"},
+		{cfg: Config{Inject: true}, want: "README.md"},
+		{cfg: Config{OutputName: "index.md"}, want: "index.md"},
+		{cfg: Config{Inject: true, OutputName: "API.md"}, want: "API.md"},
+	}
+	for _, tt := range tests {
+		g := &Gen{config: tt.cfg}
+		if got := g.docFileName(); got != tt.want {
+			t.Errorf("docFileName() with format %q, inject %v and output name %q = %q, want %q", tt.cfg.Format, tt.cfg.Inject, tt.cfg.OutputName, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "defaults", cfg: Config{Format: FormatMarkdown}},
+		{name: "output name", cfg: Config{OutputName: "README.md", Format: FormatMarkdown}},
+		{name: "output name in json", cfg: Config{OutputName: "docs.txt", Format: FormatJSON}},
+		{name: "single file", cfg: Config{SingleFile: true, Format: FormatMarkdown}},
+		{name: "inject", cfg: Config{Inject: true, Format: FormatMarkdown}},
+		{name: "directory", cfg: Config{OutputName: "docs/README.md", Format: FormatMarkdown}, wantErr: "must be a file name without directories"},
+		{name: "windows directory", cfg: Config{OutputName: `docs\README.md`, Format: FormatMarkdown}, wantErr: "must be a file name without directories"},
+		{name: "parent directory", cfg: Config{OutputName: "..", Format: FormatMarkdown}, wantErr: "must be a file name without directories"},
+		{name: "go file", cfg: Config{OutputName: "doc.go", Format: FormatMarkdown}, wantErr: "would overwrite Go sources"},
+		{name: "single file in html", cfg: Config{SingleFile: true, Format: FormatHTML}, wantErr: "single file documentation is only available in markdown"},
+		{name: "inject in json", cfg: Config{Inject: true, Format: FormatJSON}, wantErr: "injecting the documentation is only available in markdown"},
+		{name: "inject into a single file", cfg: Config{Inject: true, SingleFile: true, Format: FormatMarkdown}, wantErr: "with a file for each package"},
+	}
+	for _, tt := range tests {
+		err := (&Gen{config: tt.cfg}).checkOutput()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: checkOutput() = %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: checkOutput() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
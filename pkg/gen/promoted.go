@@ -0,0 +1,91 @@
+package gen
+
+import (
+	"bytes"
+	"go/doc/comment"
+	"go/types"
+	"sort"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/template"
+)
+
+// promotedMethods returns the function listing the exported methods the types
+// of pkg gain through their embedded fields, linked to the documentation of the
+// embedded type declaring them.
+func (g *Gen) promotedMethods(pkg *common.Pkg) func(obj *types.TypeName) []template.PromotedMethod {
+	qualifier := func(p *types.Package) string {
+		if p == pkg.Types {
+			return ""
+		}
+		return p.Name()
+	}
+
+	return func(obj *types.TypeName) []template.PromotedMethod {
+		if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+			// The methods of embedded interfaces are part of the interface.
+			return nil
+		}
+
+		value := types.NewMethodSet(obj.Type())
+		var methods []template.PromotedMethod
+		mset := types.NewMethodSet(types.NewPointer(obj.Type()))
+		for i := 0; i < mset.Len(); i++ {
+			sel := mset.At(i)
+			fn, ok := sel.Obj().(*types.Func)
+			if !ok || len(sel.Index()) < 2 || !fn.Exported() {
+				continue
+			}
+			recv := recvTypeName(fn)
+			if recv == nil {
+				continue
+			}
+
+			// Generic types are named like the instance embedded, such as
+			// Box[int], and linked like their origin.
+			from := fn.Type().(*types.Signature).Recv().Type()
+			if ptr, ok := from.(*types.Pointer); ok {
+				from = ptr.Elem()
+			}
+
+			var sig bytes.Buffer
+			types.WriteSignature(&sig, fn.Type().(*types.Signature), qualifier)
+
+			link := &comment.DocLink{Recv: recv.Name(), Name: fn.Name()}
+			if recv.Pkg() != nil {
+				link.ImportPath = recv.Pkg().Path()
+			}
+			url, ok := g.index.LinkURL(pkg, link)
+			if !ok {
+				// Methods of unexported types aren't documented.
+				url = ""
+			}
+
+			methods = append(methods, template.PromotedMethod{
+				Name:      fn.Name(),
+				Signature: fn.Name() + sig.String(),
+				From:      types.TypeString(from, qualifier),
+				URL:       url,
+				Pointer:   value.Lookup(fn.Pkg(), fn.Name()) == nil,
+			})
+		}
+		sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+		return methods
+	}
+}
+
+// recvTypeName returns the type declaring the method fn.
+func recvTypeName(fn *types.Func) *types.TypeName {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Origin().Obj()
+	}
+	return nil
+}
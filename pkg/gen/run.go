@@ -1,13 +1,19 @@
 package gen
 
 import (
+	"context"
 	"fmt"
+	"go/ast"
 	"go/doc"
 	"go/token"
+	"go/types"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"github.com/ulm0/dors/pkg/common"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -15,9 +21,18 @@ func init() {
 	log.SetReportTimestamp(false)
 }
 
-// loadPackages loads the package documentation for a given directory.
-func loadPackages(dir string, includeUnexported bool) (*doc.Package, *token.FileSet, string, error) {
-	log.Info("Loading package documentation", "dir", dir)
+// loadPackages loads the documentation of every package under rootDir with a
+// single packages.Load call per module, so that shared dependencies are parsed
+// and type-checked only once and all packages share the same token.FileSet.
+// The modules nested below rootDir, which a ./... pattern doesn't match, are
+// loaded on their own.
+// includeUnexported reports whether unexported symbols are documented for the
+// package at the given path relative to rootDir.
+func loadPackages(ctx context.Context, rootDir string, includeUnexported func(pkgPath string) bool) ([]*common.Pkg, []*PackageError, error) {
+	dirs, err := moduleDirs(rootDir)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	loadMode := packages.NeedName |
 		packages.NeedFiles |
@@ -29,57 +44,162 @@ func loadPackages(dir string, includeUnexported bool) (*doc.Package, *token.File
 		packages.NeedImports
 
 	cfg := &packages.Config{
-		Mode:  loadMode,
-		Dir:   dir,
-		Tests: false,
+		Context: ctx,
+		Mode:    loadMode,
+		Fset:    token.NewFileSet(),
+		Tests:   true,
 	}
 
-	pkgs, err := packages.Load(cfg, "./")
-	if err != nil {
-		log.Error("Error loading packages", "dir", dir, "error", err)
-		return nil, nil, "", fmt.Errorf("loading packages: %w", err)
+	var pkgs []*packages.Package
+	for _, dir := range dirs {
+		log.Info("Loading packages", "dir", dir)
+		cfg.Dir = dir
+		loaded, err := packages.Load(cfg, "./...")
+		if err != nil {
+			log.Error("Error loading packages", "dir", dir, "error", err)
+			return nil, nil, fmt.Errorf("loading packages: %w", err)
+		}
+		pkgs = append(pkgs, loaded...)
 	}
 
-	if packages.PrintErrors(pkgs) > 0 {
-		log.Error("Packages contain errors", "dir", dir)
-		return nil, nil, "", fmt.Errorf("packages contain errors")
+	// Split the loaded packages into the packages to document and the test
+	// variants holding their _test.go files.
+	var bases []*packages.Package
+	variants := make(map[string][]*packages.Package)
+	for _, p := range pkgs {
+		switch {
+		case strings.HasSuffix(p.PkgPath, ".test"):
+			// Generated test main package, nothing to document.
+		case forTest(p) != "":
+			for _, e := range p.Errors {
+				log.Warn("Test files contain errors", "package", p.PkgPath, "error", e)
+			}
+			variants[forTest(p)] = append(variants[forTest(p)], p)
+		default:
+			bases = append(bases, p)
+		}
 	}
 
-	if len(pkgs) == 0 {
-		log.Error("No packages found in directory", "dir", dir)
-		return nil, nil, "", fmt.Errorf("no packages found in %s", dir)
-	}
+	var result []*common.Pkg
+	var pkgErrs []*PackageError
+	// The fields are shared by the packages of the module, whose structs
+	// embed each other's.
+	fields := make(map[types.Object]*ast.Field)
+	for _, pk := range bases {
+		if len(pk.GoFiles) == 0 {
+			log.Warn("No files found for package", "package", pk.PkgPath)
+			continue
+		}
 
-	pk := pkgs[0]
-	log.Info("Successfully loaded package", "package", pk.Name)
+		packagePath, err := filepath.Rel(rootDir, filepath.Dir(pk.GoFiles[0]))
+		if err != nil {
+			log.Error("Failed to get relative path", "package", pk.PkgPath, "error", err)
+			pkgErrs = append(pkgErrs, &PackageError{Path: pk.PkgPath, Err: err})
+			continue
+		}
+		packagePath = filepath.ToSlash(packagePath)
+		if packagePath == "." {
+			packagePath = "" // Represent root without "."
+		}
 
-	docMode := doc.Mode(0)
-	if includeUnexported {
-		docMode = doc.AllDecls | doc.AllMethods
-	}
+		if len(pk.Errors) > 0 {
+			for _, e := range pk.Errors {
+				log.Error("Package contains errors", "package", pk.PkgPath, "error", e)
+				pkgErrs = append(pkgErrs, &PackageError{Path: packagePath, Err: e})
+			}
+			continue
+		}
 
-	docPkg, err := doc.NewFromFiles(pk.Fset, pk.Syntax, pk.Name, docMode)
-	if err != nil {
-		log.Error("Error creating documentation", "package", pk.Name, "error", err)
-		return nil, nil, "", fmt.Errorf("failed creating documentation: %w", err)
+		docMode := doc.Mode(0)
+		if includeUnexported(packagePath) {
+			docMode = doc.AllDecls | doc.AllMethods
+		}
+
+		// Function bodies and unexported fields are gone once the
+		// documentation is created.
+		strs := constStrings(pk.Syntax, pk.TypesInfo)
+		addStructFields(fields, pk.Syntax, pk.TypesInfo)
+		docs := docComments(pk.Syntax)
+
+		docPkg, err := doc.NewFromFiles(pk.Fset, docFiles(pk, variants[pk.PkgPath]), pk.PkgPath, docMode)
+		if err != nil {
+			log.Error("Error creating documentation", "package", pk.PkgPath, "error", err)
+			pkgErrs = append(pkgErrs, &PackageError{Path: packagePath, Err: err})
+			continue
+		}
+
+		var modName string
+		if pk.Module != nil {
+			modName = pk.Module.Path
+		}
+
+		result = append(result, &common.Pkg{
+			FilesSet:  pk.Fset,
+			Files:     pk.Syntax,
+			Module:    modName,
+			Package:   docPkg,
+			Path:      packagePath,
+			Types:     pk.Types,
+			TypesInfo: pk.TypesInfo,
+			Strings:   strs,
+			Fields:    fields,
+			Docs:      docs,
+		})
+		log.Info("Documentation loaded for package", "package", pk.PkgPath)
 	}
 
-	log.Info("Documentation loaded for package", "package", pk.Name)
-	return docPkg, pk.Fset, pk.Module.Path, nil
+	return result, pkgErrs, nil
 }
 
-// containsGoFiles checks if a directory contains Go files excluding test files.
-func containsGoFiles(dir string) (bool, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return false, fmt.Errorf("failed reading directory %s: %w", dir, err)
+// forTest returns the import path of the package a test variant was built for.
+// Variants have IDs such as "pkg [pkg.test]" or "pkg_test [pkg.test]".
+func forTest(p *packages.Package) string {
+	i := strings.Index(p.ID, " [")
+	if i < 0 || !strings.HasSuffix(p.ID, ".test]") {
+		return ""
 	}
+	return strings.TrimSuffix(p.ID[i+2:len(p.ID)-1], ".test")
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") {
-			return true, nil
+// docFiles returns the syntax of the base package along with the _test.go files
+// of its test variants, including the external _test package, so that
+// doc.NewFromFiles can attach the examples to their symbols.
+func docFiles(base *packages.Package, variants []*packages.Package) []*ast.File {
+	files := append([]*ast.File{}, base.Syntax...)
+	for _, p := range variants {
+		for _, f := range p.Syntax {
+			if strings.HasSuffix(p.Fset.File(f.Pos()).Name(), "_test.go") {
+				files = append(files, f)
+			}
 		}
 	}
+	return files
+}
 
-	return false, nil
+// moduleDirs returns rootDir followed by the directories of the modules nested
+// below it. The directories the go command ignores, such as testdata, vendor
+// and the ones starting with a dot or an underscore, are skipped.
+func moduleDirs(rootDir string) ([]string, error) {
+	dirs := []string{rootDir}
+	err := filepath.WalkDir(rootDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == rootDir {
+			return nil
+		}
+
+		name := d.Name()
+		if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking for nested modules: %w", err)
+	}
+	return dirs, nil
 }
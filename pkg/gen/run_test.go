@@ -0,0 +1,231 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/ulm0/dors/pkg/common"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// writeFiles creates files, mapping slash separated paths relative to dir to
+// their content.
+func writeFiles(tb testing.TB, dir string, files map[string]string) {
+	tb.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func noUnexported(string) bool { return false }
+
+// pkgPaths returns the paths of pkgs relative to the root directory.
+func pkgPaths(pkgs []*common.Pkg) []string {
+	var paths []string
+	for _, p := range pkgs {
+		paths = append(paths, p.Path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestLoadPackagesTestVariants(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"a/a.go": `// Package a is documented.
+package a
+
+// F is a function.
+func F() {}
+
+// T is a type.
+type T struct{}
+
+// M is a method.
+func (T) M() {}
+`,
+		"a/a_test.go": `package a
+
+import "testing"
+
+func ExampleF() {
+	F()
+}
+
+func TestF(t *testing.T) {}
+`,
+		"a/example_test.go": `package a_test
+
+import "example.com/m/a"
+
+func Example() {
+	a.F()
+}
+
+func ExampleT_M() {
+	a.T{}.M()
+}
+`,
+	})
+
+	pkgs, pkgErrs, err := loadPackages(context.Background(), dir, noUnexported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgErrs) > 0 {
+		t.Fatalf("loadPackages() errors = %v", pkgErrs)
+	}
+	// The test variants and the generated test main aren't documented.
+	if got := pkgPaths(pkgs); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("loadPackages() paths = %q, want [a]", got)
+	}
+
+	p := pkgs[0]
+	if len(p.Files) != 1 {
+		t.Errorf("Files holds %d files, want the one without tests", len(p.Files))
+	}
+	d := p.Package
+	if len(d.Examples) != 1 {
+		t.Errorf("package has %d examples, want 1", len(d.Examples))
+	}
+	if len(d.Funcs) != 1 || len(d.Funcs[0].Examples) != 1 {
+		t.Errorf("F has no example from the internal test file")
+	}
+	if len(d.Types) != 1 || len(d.Types[0].Methods) != 1 || len(d.Types[0].Methods[0].Examples) != 1 {
+		t.Errorf("T.M has no example from the external test package")
+	}
+}
+
+func TestLoadPackagesErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n\ngo 1.21\n",
+		"ok/ok.go": "package ok\n\n// OK is fine.\nconst OK = 1\n",
+		// Errors in test files only lose the examples.
+		"ok/ok_test.go": "package ok\n\nvar _ = undefined\n",
+		"bad/bad.go":    "package bad\n\nvar X int = \"text\"\n",
+	})
+
+	pkgs, pkgErrs, err := loadPackages(context.Background(), dir, noUnexported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pkgPaths(pkgs); !slices.Equal(got, []string{"ok"}) {
+		t.Errorf("loadPackages() paths = %q, want [ok]", got)
+	}
+	if len(pkgErrs) != 1 || pkgErrs[0].Path != "bad" {
+		t.Fatalf("loadPackages() errors = %v, want one for bad", pkgErrs)
+	}
+}
+
+func TestLoadPackagesNestedModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                "module example.com/m\n\ngo 1.21\n",
+		"a/a.go":                "package a\n",
+		"inner/go.mod":          "module example.com/inner\n\ngo 1.21\n",
+		"inner/inner.go":        "package inner\n",
+		"inner/b/b.go":          "package b\n",
+		"testdata/mod/go.mod":   "module example.com/testdata\n\ngo 1.21\n",
+		"testdata/mod/mod.go":   "package mod\n",
+		".hidden/go.mod":        "module example.com/hidden\n\ngo 1.21\n",
+		".hidden/hidden.go":     "package hidden\n",
+		"_ignored/go.mod":       "module example.com/ignored\n\ngo 1.21\n",
+		"_ignored/ignored.go":   "package ignored\n",
+		"inner/testdata/x/x.go": "package x\n",
+	})
+
+	pkgs, pkgErrs, err := loadPackages(context.Background(), dir, noUnexported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgErrs) > 0 {
+		t.Fatalf("loadPackages() errors = %v", pkgErrs)
+	}
+	if got, want := pkgPaths(pkgs), []string{"a", "inner", "inner/b"}; !slices.Equal(got, want) {
+		t.Fatalf("loadPackages() paths = %q, want %q", got, want)
+	}
+	for _, p := range pkgs {
+		want := "example.com/m"
+		if p.Path != "a" {
+			want = "example.com/inner"
+		}
+		if p.Module != want {
+			t.Errorf("package %s has module %s, want %s", p.Path, p.Module, want)
+		}
+	}
+}
+
+// writeModule generates a module with n packages, each importing the previous
+// one and a few packages of the standard library.
+func writeModule(tb testing.TB, dir string, n int) {
+	tb.Helper()
+	files := map[string]string{"go.mod": "module example.com/bench\n\ngo 1.21\n"}
+	for i := 0; i < n; i++ {
+		src := fmt.Sprintf("// Package p%[1]d is generated.\npackage p%[1]d\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n", i)
+		if i > 0 {
+			src += fmt.Sprintf("\n\t\"example.com/bench/p%d\"\n", i-1)
+		}
+		src += ")\n\n// Join formats and joins values.\nfunc Join(values ...any) string {\n"
+		if i > 0 {
+			src += fmt.Sprintf("\t_ = p%d.Join\n", i-1)
+		}
+		src += "\treturn strings.Join(strings.Fields(fmt.Sprint(values...)), \" \")\n}\n"
+		files[fmt.Sprintf("p%d/p.go", i)] = src
+	}
+	writeFiles(tb, dir, files)
+}
+
+// BenchmarkLoadPackages compares the single load of the module with a load
+// per directory, which type-checks the shared dependencies for every package.
+func BenchmarkLoadPackages(b *testing.B) {
+	const n = 20
+	dir := b.TempDir()
+	writeModule(b, dir, n)
+
+	b.Run("module", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pkgs, _, err := loadPackages(context.Background(), dir, noUnexported)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(pkgs) != n {
+				b.Fatalf("loaded %d packages, want %d", len(pkgs), n)
+			}
+		}
+	})
+
+	b.Run("directory", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < n; j++ {
+				cfg := &packages.Config{
+					Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+						packages.NeedTypes | packages.NeedTypesInfo | packages.NeedDeps |
+						packages.NeedModule | packages.NeedImports,
+					Dir: filepath.Join(dir, fmt.Sprintf("p%d", j)),
+				}
+				if _, err := packages.Load(cfg, "./"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/template"
+)
+
+// singleFileName is the name of the file documenting every package when
+// Config.OutputName is empty.
+const singleFileName = "API.md"
+
+// renderSingleFile combines the documentation of the packages into a single
+// file, with a section for each package whose headings are shifted down a level.
+func (g *Gen) renderSingleFile(allPackages []*common.Pkg, files []docFile) (docFile, error) {
+	title := g.config.Title
+	if title == "" {
+		title = "API Documentation"
+	}
+
+	data := &template.SingleFileData{
+		Title:    title,
+		SubPkgs:  topLevelPkgs(allPackages),
+		Sections: make(map[*common.Pkg]string, len(files)),
+	}
+	var unresolved []string
+	for _, f := range files {
+		if f.pkg == nil {
+			continue
+		}
+		if f.pkg.Path == "" {
+			data.Root = f.pkg
+		}
+		data.Sections[f.pkg] = shiftHeadings(string(f.Content))
+		for _, ref := range f.Unresolved {
+			if !slices.Contains(unresolved, ref) {
+				unresolved = append(unresolved, ref)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	opts := template.Options{Custom: g.templateFS(g.config), SingleFile: true}
+	if err := template.Execute(&buf, data, g.config, opts); err != nil {
+		return docFile{}, fmt.Errorf("rendering single file documentation: %w", err)
+	}
+
+	name := g.config.OutputName
+	if name == "" {
+		name = singleFileName
+	}
+	return docFile{Path: path.Join(g.outDir(), name), Content: buf.Bytes(), Unresolved: unresolved}, nil
+}
+
+// shiftHeadings moves the markdown headings of s down a level, leaving the
+// code blocks untouched.
+func shiftHeadings(s string) string {
+	lines := strings.Split(s, "\n")
+	var fenced, pre bool
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "```"):
+			fenced = !fenced
+		case fenced:
+		case pre:
+			pre = !strings.Contains(line, "</pre>")
+		case strings.HasPrefix(line, "<pre>"):
+			pre = !strings.Contains(line, "</pre>")
+		case strings.HasPrefix(line, "#"):
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			if level < 6 && strings.HasPrefix(line[level:], " ") {
+				lines[i] = "#" + line
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
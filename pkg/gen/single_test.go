@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShiftHeadings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "headings", in: "# Package\n\n## Types\n\n#### Fields", want: "## Package\n\n### Types\n\n##### Fields"},
+		{name: "deepest level", in: "###### Deep", want: "###### Deep"},
+		{name: "not headings", in: "#hashtag\n#\n text # inline", want: "#hashtag\n#\n text # inline"},
+		{name: "anchored heading", in: `### <a id="T"></a>type T`, want: `#### <a id="T"></a>type T`},
+		{name: "fenced block", in: "```sh\n# comment\n```\n# After", want: "```sh\n# comment\n```\n## After"},
+		{name: "pre block", in: "<pre>\n# comment\n## another\n</pre>\n# After", want: "<pre>\n# comment\n## another\n</pre>\n## After"},
+		{name: "pre line", in: "<pre>type T</pre>\n# After", want: "<pre>type T</pre>\n## After"},
+		{name: "pre closed on its last line", in: "<pre>type T struct {\n# x\n}</pre>\n# After", want: "<pre>type T struct {\n# x\n}</pre>\n## After"},
+	}
+	for _, tt := range tests {
+		if got := shiftHeadings(tt.in); got != tt.want {
+			t.Errorf("%s: shiftHeadings(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"m.go": `// Package m is the root package.
+//
+// # Usage
+//
+// Run it:
+//
+//	# not a heading
+//	m run
+package m
+
+// Root is declared in the root package, see [sub.Sub] and [Missing].
+type Root struct{}
+`,
+		"sub/sub.go": "// Package sub is a sub-package.\npackage sub\n\n// Sub does things, see [m.Root] and [Unknown].\nfunc Sub() {}\n",
+	})
+
+	cfg := Config{IncludeSections: allSections, SingleFile: true, OutDir: "docs", Title: "M"}
+	result, err := New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Generate() errors = %v", result.Errors)
+	}
+	if want := []string{"docs/API.md"}; !reflect.DeepEqual(result.Files, want) {
+		t.Errorf("Generate() files = %q, want %q", result.Files, want)
+	}
+	want := []UnresolvedLink{{File: "docs/API.md", Link: "[Missing]"}, {File: "docs/API.md", Link: "[Unknown]"}}
+	if !reflect.DeepEqual(result.Unresolved, want) {
+		t.Errorf("Generate() unresolved = %v, want %v", result.Unresolved, want)
+	}
+
+	api := readFile(t, dir, "docs/API.md")
+	for _, want := range []string{
+		"# M\n",
+		"* [m](#example.com/m): Package m is the root package.",
+		"* [sub](#example.com/m/sub): Package sub is a sub-package.",
+		"<a id=\"example.com/m\"></a>\n\n## Package `m`",
+		"### Usage",
+		"```go\n# not a heading\nm run\n```",
+		"#### <a id=\"example.com/m.Root\"></a>type [`Root`](../m.go#L12)",
+		"[sub.Sub](#example.com/m/sub.Sub)",
+		"<a id=\"example.com/m/sub\"></a>\n\n## Package `sub`",
+		"#### <a id=\"example.com/m/sub.Sub\"></a>func [`Sub`](../sub/sub.go#L5)",
+		"[m.Root](#example.com/m.Root)",
+	} {
+		if !strings.Contains(api, want) {
+			t.Errorf("API.md doesn't contain %q:\n%s", want, api)
+		}
+	}
+	if strings.Contains(api, "DOCS.md") {
+		t.Errorf("API.md links to the files of the packages:\n%s", api)
+	}
+}
@@ -0,0 +1,259 @@
+package gen
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Anchor styles of the markdown headings.
+const (
+	// AnchorsHTML adds an <a id="Name"></a> anchor named after the symbol to
+	// its heading.
+	AnchorsHTML = "html"
+	// AnchorsGitHub links to the IDs GitHub generates from the heading text.
+	AnchorsGitHub = "github"
+	// AnchorsGitLab links to the IDs GitLab generates from the heading text.
+	AnchorsGitLab = "gitlab"
+	// AnchorsCommonMark links to the IDs generated by CommonMark renderers such
+	// as markdown-it-anchor, which keep the punctuation.
+	AnchorsCommonMark = "commonmark"
+)
+
+// checkAnchors verifies the anchor style, which can't be changed by the
+// overrides since the links cross the packages.
+func (g *Gen) checkAnchors() error {
+	switch g.config.Anchors {
+	case "":
+		g.config.Anchors = AnchorsHTML
+	case AnchorsHTML, AnchorsGitHub, AnchorsGitLab, AnchorsCommonMark:
+	default:
+		return fmt.Errorf("unknown anchors %q, expected %s, %s, %s or %s", g.config.Anchors, AnchorsHTML, AnchorsGitHub, AnchorsGitLab, AnchorsCommonMark)
+	}
+
+	for _, o := range g.config.Overrides {
+		if a := g.configFor(o.Path).Anchors; a != g.config.Anchors {
+			return fmt.Errorf("override %s: the anchors cannot be overridden", o.Path)
+		}
+	}
+	return nil
+}
+
+var (
+	// anchorRx matches the anchors added by the templates.
+	anchorRx = regexp.MustCompile(`<a id="([^"]*)"></a>`)
+	// headingRx matches an ATX heading.
+	headingRx = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	// fragmentLinkRx matches the markdown and HTML links with a fragment.
+	fragmentLinkRx = regexp.MustCompile(`(\]\(|href=")([^()"\s#]*)#([^()"\s]+)`)
+)
+
+// slugAnchors replaces the anchors of the headings of the markdown files with
+// the IDs generated from the heading text in the given style, and rewrites the
+// links to those anchors. Only the documentation between the markers of the
+// injected files is changed.
+func slugAnchors(files []docFile, style string) {
+	slugs := make(map[string]map[string]string, len(files))
+	for i := range files {
+		var ids map[string]string
+		content := string(files[i].Content)
+		files[i].Content, ids = headingSlugs(content, style, generatedLines(content, files[i].injected))
+		slugs[files[i].Path] = ids
+	}
+
+	for i := range files {
+		from := files[i].Path
+		content := string(files[i].Content)
+		lines := strings.SplitAfter(content, "\n")
+		generated := generatedLines(content, files[i].injected)
+		for j, line := range lines {
+			if !generated[j] {
+				continue
+			}
+			lines[j] = fragmentLinkRx.ReplaceAllStringFunc(line, func(link string) string {
+				m := fragmentLinkRx.FindStringSubmatch(link)
+				target := from
+				if m[2] != "" {
+					target = path.Join(path.Dir(from), m[2])
+				}
+				slug, ok := slugs[target][m[3]]
+				if !ok {
+					return link
+				}
+				return m[1] + m[2] + "#" + slug
+			})
+		}
+		files[i].Content = []byte(strings.Join(lines, ""))
+	}
+}
+
+// headingSlugs removes the anchors from the headings of content, and the lines
+// holding only anchors right before a heading. It returns the content and the
+// IDs generated for the headings, keyed by the anchors they replace. Only the
+// generated lines are changed, the headings of the others still count for the
+// unique IDs.
+func headingSlugs(content, style string, generated []bool) ([]byte, map[string]string) {
+	ids := make(map[string]string)
+	seen := make(map[string]int)
+	var pending []string
+
+	lines := strings.Split(content, "\n")
+	var out []string
+	var fenced bool
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fenced = !fenced
+		case fenced:
+		case !generated[i]:
+			if m := headingRx.FindStringSubmatch(line); m != nil {
+				uniqueSlug(slugify(headingText(m[2]), style), seen)
+			}
+		case trimmed != "" && anchorRx.ReplaceAllString(trimmed, "") == "" && nextIsHeading(lines[i+1:]):
+			for _, m := range anchorRx.FindAllStringSubmatch(trimmed, -1) {
+				pending = append(pending, m[1])
+			}
+			continue
+		case headingRx.MatchString(line):
+			for _, m := range anchorRx.FindAllStringSubmatch(line, -1) {
+				pending = append(pending, m[1])
+			}
+			line = anchorRx.ReplaceAllString(line, "")
+			m := headingRx.FindStringSubmatch(line)
+			slug := uniqueSlug(slugify(headingText(m[2]), style), seen)
+			for _, id := range pending {
+				ids[id] = slug
+			}
+			pending = nil
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n")), ids
+}
+
+// nextIsHeading reports whether the first line of lines that isn't blank is a heading.
+func nextIsHeading(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return headingRx.MatchString(line)
+		}
+	}
+	return false
+}
+
+// headingText returns the text of a heading as rendered, without its links,
+// code spans and HTML tags.
+func headingText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '`':
+			// Code spans are kept as they are.
+			end := strings.IndexByte(s[i+1:], '`')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '\\' && i+1 < len(s) && unicode.IsPunct(rune(s[i+1])):
+			i++
+			b.WriteByte(s[i])
+		case c == '[':
+		case c == ']' && strings.HasPrefix(s[i+1:], "("):
+			// The destination of the link.
+			end := strings.IndexByte(s[i:], ')')
+			if end < 0 {
+				end = len(s) - i
+			}
+			i += end
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// slugify returns the ID generated from the text of a heading.
+func slugify(text, style string) string {
+	text = strings.ToLower(text)
+	var b strings.Builder
+	switch style {
+	case AnchorsCommonMark:
+		// markdown-it-anchor: encodeURIComponent(text.trim().toLowerCase().replace(/\s+/g, '-'))
+		for i, field := range strings.Fields(text) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteString(encodeURIComponent(field))
+		}
+		return b.String()
+	case AnchorsGitLab:
+		// Non-word characters are dropped, spaces become hyphens and the
+		// repeated hyphens are collapsed.
+		for _, r := range text {
+			switch {
+			case r == ' ' || r == '-':
+				if !strings.HasSuffix(b.String(), "-") {
+					b.WriteByte('-')
+				}
+			case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	default:
+		// GitHub drops the punctuation and turns every space into a hyphen.
+		for _, r := range text {
+			switch {
+			case r == ' ':
+				b.WriteByte('-')
+			case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+}
+
+// uniqueSlug suffixes slug with a counter when a previous heading has the same
+// ID, like the renderers do: "example", "example-1", "example-2".
+func uniqueSlug(slug string, seen map[string]int) string {
+	unique := slug
+	if n, ok := seen[slug]; ok {
+		for {
+			n++
+			unique = fmt.Sprintf("%s-%d", slug, n)
+			if _, taken := seen[unique]; !taken {
+				break
+			}
+		}
+		seen[slug] = n
+	}
+	seen[unique] = 0
+	return unique
+}
+
+// encodeURIComponent escapes s like the JavaScript function of the same name.
+func encodeURIComponent(s string) string {
+	const unreserved = "-_.!~*'()"
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c < 0x80 && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(unreserved, c) >= 0) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
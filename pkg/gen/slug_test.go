@@ -0,0 +1,80 @@
+package gen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		text  string
+		style string
+		want  string
+	}{
+		{text: "Functions", style: AnchorsGitHub, want: "functions"},
+		{text: "func (T) String", style: AnchorsGitHub, want: "func-t-string"},
+		{text: "Hello, World!", style: AnchorsGitHub, want: "hello-world"},
+		{text: "a - b", style: AnchorsGitHub, want: "a---b"},
+		{text: "C++ & Go", style: AnchorsGitHub, want: "c--go"},
+		{text: "snake_case and kebab-case", style: AnchorsGitHub, want: "snake_case-and-kebab-case"},
+		{text: "Ünïcödé Straße", style: AnchorsGitHub, want: "ünïcödé-straße"},
+		{text: "café 日本語", style: AnchorsGitHub, want: "café-日本語"},
+		{text: "!!!", style: AnchorsGitHub, want: ""},
+
+		{text: "func (T) String", style: AnchorsGitLab, want: "func-t-string"},
+		{text: "a - b", style: AnchorsGitLab, want: "a-b"},
+		{text: "C++ & Go", style: AnchorsGitLab, want: "c-go"},
+		{text: "snake_case and kebab--case", style: AnchorsGitLab, want: "snake_case-and-kebab-case"},
+		{text: "Ünïcödé Straße", style: AnchorsGitLab, want: "ünïcödé-straße"},
+
+		{text: "func (T) String", style: AnchorsCommonMark, want: "func-(t)-string"},
+		{text: "Hello,  World!", style: AnchorsCommonMark, want: "hello%2C-world!"},
+		{text: "a & b?", style: AnchorsCommonMark, want: "a-%26-b%3F"},
+		{text: "Straße 日本", style: AnchorsCommonMark, want: "stra%C3%9Fe-%E6%97%A5%E6%9C%AC"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.text, tt.style); got != tt.want {
+			t.Errorf("slugify(%q, %s) = %q, want %q", tt.text, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	tests := []struct {
+		slugs []string
+		want  []string
+	}{
+		{slugs: []string{"a", "b"}, want: []string{"a", "b"}},
+		{slugs: []string{"example", "example", "example"}, want: []string{"example", "example-1", "example-2"}},
+		// A suffixed heading takes the ID the next duplicate would get.
+		{slugs: []string{"a-1", "a", "a"}, want: []string{"a-1", "a", "a-2"}},
+		{slugs: []string{"a", "a", "a-1"}, want: []string{"a", "a-1", "a-1-1"}},
+		{slugs: []string{"", ""}, want: []string{"", "-1"}},
+	}
+	for _, tt := range tests {
+		seen := make(map[string]int)
+		var got []string
+		for _, slug := range tt.slugs {
+			got = append(got, uniqueSlug(slug, seen))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("uniqueSlug(%q) = %q, want %q", tt.slugs, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingText(t *testing.T) {
+	tests := map[string]string{
+		"Functions":                          "Functions",
+		"type [`Root`](m.go#L5)":             "type Root",
+		"func [Types](m.go#L8)":              "func Types",
+		`<a id="Root"></a>type Root`:         "type Root",
+		"func (`*T[K]`) Get":                 "func (*T[K]) Get",
+		`escaped \*stars\* and \[brackets\]`: "escaped *stars* and [brackets]",
+	}
+	for heading, want := range tests {
+		if got := headingText(heading); got != want {
+			t.Errorf("headingText(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
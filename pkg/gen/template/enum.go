@@ -0,0 +1,70 @@
+package template
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/types"
+)
+
+// enum holds the constants of a type following the enum pattern.
+type enum struct {
+	Values []enumValue
+	// HasStrings reports whether the output of the String method is known for
+	// any of the values.
+	HasStrings bool
+}
+
+// enumValue is a constant of an enum.
+type enumValue struct {
+	Name string
+	// Value is the exact value of the constant, with iota and implicit
+	// repetitions resolved.
+	Value string
+	// String is the output of the String method, if known.
+	String string
+	Doc    string
+}
+
+// enumValues returns the constants declared with type t, or nil when t doesn't
+// follow the enum pattern: a basic integer or string type with at least two
+// constants of its own.
+func enumValues(info *types.Info, strs map[types.Object]string, t *doc.Type) *enum {
+	obj := typeName(info, t)
+	if obj == nil {
+		return nil
+	}
+	basic, ok := obj.Type().Underlying().(*types.Basic)
+	if !ok || basic.Info()&(types.IsInteger|types.IsString) == 0 {
+		return nil
+	}
+
+	e := &enum{}
+	for _, v := range t.Consts {
+		for _, s := range v.Decl.Specs {
+			spec, ok := s.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for _, name := range spec.Names {
+				c, ok := info.Defs[name].(*types.Const)
+				if !ok || !types.Identical(c.Type(), obj.Type()) {
+					continue
+				}
+				value := enumValue{
+					Name:  name.Name,
+					Value: tableCell(c.Val().ExactString()),
+					Doc:   specDoc(spec),
+				}
+				if str, ok := strs[c]; ok {
+					value.String = tableCell(str)
+					e.HasStrings = true
+				}
+				e.Values = append(e.Values, value)
+			}
+		}
+	}
+	if len(e.Values) < 2 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,237 @@
+package template
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/ulm0/dors/pkg/common"
+)
+
+// fieldTable holds the exported fields of a struct type, including the ones
+// promoted from its embedded structs.
+type fieldTable struct {
+	Fields []structField
+	// Tags are the keys of the struct tags found in the fields, in order of
+	// appearance, each one rendered as a column.
+	Tags []string
+	// HasDefaults reports whether any field documents its default value.
+	HasDefaults bool
+}
+
+// structField is a row of a fieldTable, its values are table cells.
+type structField struct {
+	Name string
+	Type string
+	// Tags holds the value of each of the fieldTable tags.
+	Tags []string
+	// Default is the value given in a "Default:" line of the field doc.
+	Default string
+	// Doc is the doc of the field, noting the embedded fields it's promoted from.
+	Doc string
+}
+
+// promotedField is a field found while flattening the embedded structs.
+type promotedField struct {
+	v     *types.Var
+	tag   string
+	from  string
+	depth int
+	// ambiguous is set when several fields at the same depth have the same
+	// name, so none of them is promoted.
+	ambiguous bool
+}
+
+// structFields returns the fields of the struct type t, or nil when t isn't a
+// struct or has no exported fields. syntax maps the fields of the module to
+// their declaration, which holds their doc; the fields of packages outside the
+// module are described with their type only. The deprecated fields are left
+// out when hideDeprecated is set.
+func structFields(fset *token.FileSet, info *types.Info, syntax map[types.Object]*ast.Field, typeURL func(obj types.Object) string, hideDeprecated bool, t *doc.Type) *fieldTable {
+	obj := typeName(info, t)
+	if obj == nil {
+		return nil
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	found := make(map[string]*promotedField)
+	var walk func(st *types.Struct, from string, depth int, seen map[types.Type]bool)
+	walk = func(st *types.Struct, from string, depth int, seen map[types.Type]bool) {
+		for i := 0; i < st.NumFields(); i++ {
+			v := st.Field(i)
+			if v.Embedded() {
+				typ := v.Type()
+				if ptr, ok := typ.(*types.Pointer); ok {
+					typ = ptr.Elem()
+				}
+				if embedded, ok := typ.Underlying().(*types.Struct); ok {
+					if seen[typ] {
+						continue
+					}
+					seen[typ] = true
+					walk(embedded, strings.TrimPrefix(from+"."+v.Name(), "."), depth+1, seen)
+					delete(seen, typ)
+					continue
+				}
+			}
+			if !v.Exported() {
+				continue
+			}
+
+			f := &promotedField{v: v, tag: st.Tag(i), from: from, depth: depth}
+			prev, ok := found[v.Name()]
+			switch {
+			case !ok:
+				names = append(names, v.Name())
+				found[v.Name()] = f
+			case depth < prev.depth:
+				found[v.Name()] = f
+			case depth == prev.depth:
+				prev.ambiguous = true
+			}
+		}
+	}
+	walk(st, "", 0, map[types.Type]bool{obj.Type(): true})
+
+	table := &fieldTable{}
+	tagIndex := make(map[string]int)
+	for _, name := range names {
+		f := found[name]
+		if f.ambiguous {
+			continue
+		}
+
+		field := structField{Name: name}
+		decl := syntax[f.v]
+		if decl != nil && hideDeprecated {
+			if _, deprecated := common.Deprecation(decl.Doc.Text()); deprecated {
+				continue
+			}
+		}
+		if decl != nil {
+			text := decl.Doc.Text()
+			if text == "" {
+				text = decl.Comment.Text()
+			}
+			field.Default, text = fieldDefault(text)
+			field.Doc = tableCell(strings.Join(strings.Fields(text), " "))
+		}
+		switch {
+		case decl != nil && f.v.Pkg() == obj.Pkg():
+			var typ strings.Builder
+			if err := printer.Fprint(&typ, fset, decl.Type); err != nil {
+				log.Errorf("Error printing field type: %v", err)
+			}
+			if typeURL != nil {
+				field.Type = "<code>" + tableCell(linkHTML(typ.String(), []ast.Node{decl.Type}, info, typeURL)) + "</code>"
+			} else {
+				field.Type = "`" + tableCell(typ.String()) + "`"
+			}
+		default:
+			// The syntax of other packages names the types relative to them.
+			typ := types.TypeString(f.v.Type(), func(p *types.Package) string {
+				if p == obj.Pkg() {
+					return ""
+				}
+				return p.Name()
+			})
+			field.Type = "`" + tableCell(typ) + "`"
+		}
+		if field.Default != "" {
+			table.HasDefaults = true
+		}
+		if f.from != "" {
+			field.Doc = strings.TrimSpace(field.Doc + " Promoted from `" + f.from + "`.")
+		}
+
+		field.Tags = make([]string, len(table.Tags))
+		for _, kv := range parseTags(f.tag) {
+			i, ok := tagIndex[kv[0]]
+			if !ok {
+				i = len(table.Tags)
+				tagIndex[kv[0]] = i
+				table.Tags = append(table.Tags, kv[0])
+				field.Tags = append(field.Tags, "")
+			}
+			field.Tags[i] = "`" + tableCell(kv[1]) + "`"
+		}
+		table.Fields = append(table.Fields, field)
+	}
+	if len(table.Fields) == 0 {
+		return nil
+	}
+
+	// Tags found in later fields add columns to the earlier ones.
+	for i := range table.Fields {
+		for len(table.Fields[i].Tags) < len(table.Tags) {
+			table.Fields[i].Tags = append(table.Fields[i].Tags, "")
+		}
+	}
+	return table
+}
+
+// fieldDefault extracts the value of a "Default:" line from the doc of a
+// field, returning the value and the doc without that line.
+func fieldDefault(text string) (string, string) {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "Default:")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSuffix(strings.TrimSpace(value), ".")
+		rest := append(lines[:i:i], lines[i+1:]...)
+		return tableCell(value), strings.Join(rest, "\n")
+	}
+	return "", text
+}
+
+// parseTags returns the key and value pairs of a struct tag, following the
+// conventional format parsed by reflect.StructTag.
+func parseTags(tag string) [][2]string {
+	var tags [][2]string
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tag = tag[i+1:]
+		tags = append(tags, [2]string{key, value})
+	}
+	return tags
+}
@@ -0,0 +1,127 @@
+package template
+
+import (
+	"embed"
+	"go/ast"
+	"go/token"
+	"go/types"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	"text/template"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/markdown"
+)
+
+//go:embed *.html.gotmpl
+var htmlFiles embed.FS
+
+// Page is a page of the HTML site, rendered with the *.html.gotmpl templates.
+// The templates are executed with html/template, which escapes the values they
+// print, the doc comments and declarations are rendered as HTML by docHTML,
+// inlineDocHTML, declHTML, funcHTML and gocodeHTML.
+type Page struct {
+	// Title of the site, linking to its index page.
+	Title string
+	// Pkg is the package documented by the page, nil for the index page
+	// listing the packages when the root directory has no package.
+	Pkg *common.Pkg
+	// Root is the relative path from the page to the root of the site.
+	Root string
+	// Nav is the package tree of the sidebar, with links relative to the page.
+	Nav []*NavItem
+}
+
+// NavItem is a package of the sidebar.
+type NavItem struct {
+	// Name is the path of the package relative to its parent in the tree.
+	Name     string
+	Link     string
+	Synopsis string
+	// Current is set on the package documented by the page.
+	Current  bool
+	Children []*NavItem
+}
+
+// parseHTMLTemplates parses the *.html.gotmpl templates like parseTemplates,
+// returning an html/template set.
+func parseHTMLTemplates(name string, funcMap htmltemplate.FuncMap, custom fs.FS) (*htmltemplate.Template, error) {
+	trees, err := parseTrees(name, template.FuncMap(funcMap), custom, "*.html.gotmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := htmltemplate.New(name).Funcs(funcMap)
+	for n, tree := range trees {
+		if _, err := templates.AddParseTree(n, tree); err != nil {
+			return nil, err
+		}
+	}
+	// Unlike text/template, the template created by New doesn't get the tree
+	// added under its name.
+	return templates.Lookup(name), nil
+}
+
+// htmlFuncs returns the functions of the HTML templates, the ones of the
+// markdown templates where the functions rendering HTML return it as
+// htmltemplate.HTML, so it's printed as is.
+func htmlFuncs(cfg interface{}, pkg *common.Pkg, opts Options) htmltemplate.FuncMap {
+	funcMap := htmltemplate.FuncMap(funcs(cfg, pkg, opts))
+	for _, name := range []string{"docHTML", "inlineDocHTML", "gocodeHTML", "anchor"} {
+		f := funcMap[name].(func(string) string)
+		funcMap[name] = func(s string) htmltemplate.HTML {
+			return htmltemplate.HTML(f(s))
+		}
+	}
+	declHTML := funcMap["declHTML"].(func(*ast.GenDecl, ...ast.Spec) string)
+	funcMap["declHTML"] = func(decl *ast.GenDecl, specs ...ast.Spec) htmltemplate.HTML {
+		return htmltemplate.HTML(declHTML(decl, specs...))
+	}
+	funcHTML := funcMap["funcHTML"].(func(*ast.FuncDecl) string)
+	funcMap["funcHTML"] = func(decl *ast.FuncDecl) htmltemplate.HTML {
+		return htmltemplate.HTML(funcHTML(decl))
+	}
+	return funcMap
+}
+
+// docHTML renders a doc comment as HTML.
+func docHTML(s string, opts []markdown.Option) string {
+	var b strings.Builder
+	markdown.ToHTML(&b, s, opts...)
+	return b.String()
+}
+
+// inlineDocHTML renders a doc comment of a single paragraph as inline HTML.
+func inlineDocHTML(s string, opts []markdown.Option) string {
+	html := strings.TrimSpace(docHTML(s, opts))
+	html = strings.TrimPrefix(html, "<p>")
+	return strings.TrimSuffix(html, "</p>")
+}
+
+// highlightedCode renders src, the printed form of nodes, as a highlighted
+// HTML block whose references to types and packages link to their documentation.
+func highlightedCode(src string, nodes []ast.Node, info *types.Info, typeURL func(obj types.Object) string) string {
+	return `<pre class="code">` + codeHTML(src, nodes, info, typeURL, true) + "</pre>\n"
+}
+
+// declHTML renders a declaration as a highlighted HTML block.
+func declHTML(set *token.FileSet, info *types.Info, typeURL func(obj types.Object) string, decl *ast.GenDecl, specs ...ast.Spec) string {
+	if len(specs) == 0 {
+		specs = decl.Specs
+	}
+	nodes := make([]ast.Node, len(specs))
+	for i, spec := range specs {
+		nodes[i] = spec
+	}
+	return highlightedCode(fmtDeclaration(set, decl, specs...), nodes, info, typeURL)
+}
+
+// funcHTML renders the signature of a function as a highlighted HTML block.
+func funcHTML(set *token.FileSet, info *types.Info, typeURL func(obj types.Object) string, decl *ast.FuncDecl) string {
+	nodes := []ast.Node{decl.Name, decl.Type}
+	if decl.Recv != nil {
+		nodes = append([]ast.Node{decl.Recv}, nodes...)
+	}
+	return highlightedCode(funcSignature(set, decl), nodes, info, typeURL)
+}
@@ -0,0 +1,291 @@
+package template
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/markdown"
+)
+
+// SchemaVersion is the version of the JSON documents, it changes only when a
+// field is removed or changes its meaning. The schema is published in
+// schema/dors.schema.json.
+const SchemaVersion = 1
+
+// Document is the JSON documentation of one or several packages.
+type Document struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	Module        string     `json:"module,omitempty"`
+	Packages      []*Package `json:"packages"`
+}
+
+// Package is the JSON documentation of a package.
+type Package struct {
+	Name       string `json:"name"`
+	ImportPath string `json:"importPath"`
+	// Path is the directory of the package relative to the root directory.
+	Path     string     `json:"path"`
+	Synopsis string     `json:"synopsis"`
+	Doc      Doc        `json:"doc"`
+	Files    []string   `json:"files"`
+	Consts   []*Value   `json:"consts"`
+	Vars     []*Value   `json:"vars"`
+	Funcs    []*Func    `json:"funcs"`
+	Types    []*Type    `json:"types"`
+	Examples []*Example `json:"examples"`
+}
+
+// Doc is a doc comment, as written and rendered as markdown.
+type Doc struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown"`
+}
+
+// Position is the location of a declaration.
+type Position struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Symbol holds the fields shared by every documented symbol.
+type Symbol struct {
+	// ID is the anchor of the symbol, such as "Config" or "Gen.Generate".
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Kind is one of common.Kinds, the package and the fields aren't symbols
+	// of their own in the JSON documents.
+	Kind      string   `json:"kind"`
+	Signature string   `json:"signature"`
+	Doc       Doc      `json:"doc"`
+	Position  Position `json:"position"`
+	// Deprecated is set when the doc has a "Deprecated: " paragraph, whose
+	// text is held by Deprecation.
+	Deprecated  bool   `json:"deprecated"`
+	Deprecation string `json:"deprecation,omitempty"`
+	// References are the types the declaration refers to.
+	References []Reference `json:"references"`
+}
+
+// Reference is a type referenced by a declaration, identified by the import
+// path of its package and its ID in the document of the package.
+type Reference struct {
+	// Name of the type, qualified with its package name when it's declared in
+	// another package.
+	Name       string `json:"name"`
+	ImportPath string `json:"importPath,omitempty"`
+	// ID of the type, like Symbol.ID, empty for the predeclared types.
+	ID string `json:"id,omitempty"`
+	// URL of the documentation of the type on pkg.go.dev, empty when it has none.
+	URL string `json:"url,omitempty"`
+}
+
+// Value is a group of constants or variables.
+type Value struct {
+	Symbol
+	// Names are the names declared by the group, Name is the first one.
+	Names []string `json:"names"`
+}
+
+// Func is a function or method.
+type Func struct {
+	Symbol
+	// Recv is the receiver type of methods.
+	Recv     string     `json:"recv,omitempty"`
+	Examples []*Example `json:"examples"`
+}
+
+// Type is a type with its associated declarations.
+type Type struct {
+	Symbol
+	Consts   []*Value   `json:"consts"`
+	Vars     []*Value   `json:"vars"`
+	Funcs    []*Func    `json:"funcs"`
+	Methods  []*Func    `json:"methods"`
+	Examples []*Example `json:"examples"`
+}
+
+// Example is a testable example.
+type Example struct {
+	Name   string `json:"name"`
+	Suffix string `json:"suffix,omitempty"`
+	Doc    Doc    `json:"doc"`
+	Code   string `json:"code"`
+	Output string `json:"output,omitempty"`
+}
+
+// NewPackage builds the JSON documentation of pkg, from the same data the
+// templates consume. Only the Markdown and TypeURL options are used.
+func NewPackage(pkg *common.Pkg, skipExamples bool, opts Options) *Package {
+	b := &jsonBuilder{
+		set:          pkg.FilesSet,
+		pkg:          pkg.Types,
+		info:         pkg.TypesInfo,
+		opts:         opts,
+		skipExamples: skipExamples,
+	}
+	p := pkg.Package
+
+	out := &Package{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+		Path:       pkg.Path,
+		Synopsis:   pkg.Synopsis(),
+		Doc:        b.doc(p.Doc),
+		Files:      []string{},
+		Consts:     b.values(p.Consts, common.KindConst),
+		Vars:       b.values(p.Vars, common.KindVar),
+		Funcs:      b.funcs(p.Funcs, ""),
+		Types:      []*Type{},
+		Examples:   b.examples(p.Examples),
+	}
+	for _, f := range p.Filenames {
+		out.Files = append(out.Files, filepath.Base(f))
+	}
+	for _, t := range p.Types {
+		out.Types = append(out.Types, &Type{
+			Symbol:   b.symbol(t.Name, t.Name, common.KindType, fmtDeclaration(b.set, t.Decl), t.Doc, t.Decl.TokPos, specNodes(t.Decl)),
+			Consts:   b.values(t.Consts, common.KindConst),
+			Vars:     b.values(t.Vars, common.KindVar),
+			Funcs:    b.funcs(t.Funcs, ""),
+			Methods:  b.funcs(t.Methods, t.Name),
+			Examples: b.examples(t.Examples),
+		})
+	}
+	return out
+}
+
+// jsonBuilder builds the JSON documentation of a package.
+type jsonBuilder struct {
+	set          *token.FileSet
+	pkg          *types.Package
+	info         *types.Info
+	opts         Options
+	skipExamples bool
+}
+
+func (b *jsonBuilder) doc(text string) Doc {
+	var md strings.Builder
+	markdown.ToMarkdown(&md, text, b.opts.Markdown...)
+	return Doc{Text: text, Markdown: md.String()}
+}
+
+// symbol returns the fields shared by the symbols, the references are looked
+// up in the nodes of the declaration.
+func (b *jsonBuilder) symbol(id, name, kind, signature, text string, pos token.Pos, nodes []ast.Node) Symbol {
+	deprecation, deprecated := common.Deprecation(text)
+	return Symbol{
+		ID:          id,
+		Name:        name,
+		Kind:        kind,
+		Signature:   signature,
+		Doc:         b.doc(text),
+		Position:    Position{File: filename(b.set, pos), Line: lineNumber(b.set, pos)},
+		Deprecated:  deprecated,
+		Deprecation: deprecation,
+		References:  b.references(nodes),
+	}
+}
+
+func (b *jsonBuilder) values(values []*doc.Value, kind string) []*Value {
+	out := []*Value{}
+	for _, v := range values {
+		out = append(out, &Value{
+			Symbol: b.symbol(v.Names[0], v.Names[0], kind, fmtDeclaration(b.set, v.Decl), v.Doc, v.Decl.Pos(), specNodes(v.Decl)),
+			Names:  v.Names,
+		})
+	}
+	return out
+}
+
+func (b *jsonBuilder) funcs(funcs []*doc.Func, recvType string) []*Func {
+	out := []*Func{}
+	for _, f := range funcs {
+		kind := common.KindFunc
+		if f.Recv != "" {
+			kind = common.KindMethod
+		}
+		var nodes []ast.Node
+		if f.Decl.Recv != nil {
+			nodes = append(nodes, f.Decl.Recv)
+		}
+		nodes = append(nodes, f.Decl.Type)
+		out = append(out, &Func{
+			Symbol:   b.symbol(common.SymbolID(recvType, f.Name), f.Name, kind, funcSignature(b.set, f.Decl), f.Doc, f.Decl.Type.Func, nodes),
+			Recv:     f.Recv,
+			Examples: b.examples(f.Examples),
+		})
+	}
+	return out
+}
+
+func (b *jsonBuilder) examples(examples []*doc.Example) []*Example {
+	out := []*Example{}
+	if b.skipExamples {
+		return out
+	}
+	for _, ex := range examples {
+		out = append(out, &Example{
+			Name:   ex.Name,
+			Suffix: ex.Suffix,
+			Doc:    b.doc(ex.Doc),
+			Code:   exampleCode(b.set, ex),
+			Output: ex.Output,
+		})
+	}
+	return out
+}
+
+// specNodes returns the specs of decl as nodes.
+func specNodes(decl *ast.GenDecl) []ast.Node {
+	nodes := make([]ast.Node, len(decl.Specs))
+	for i, spec := range decl.Specs {
+		nodes[i] = spec
+	}
+	return nodes
+}
+
+// references returns the types referenced in nodes, in order of appearance.
+func (b *jsonBuilder) references(nodes []ast.Node) []Reference {
+	refs := []Reference{}
+	if b.info == nil {
+		return refs
+	}
+
+	seen := make(map[types.Object]bool)
+	for _, n := range nodes {
+		ast.Inspect(n, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj, ok := b.info.Uses[id].(*types.TypeName)
+			if !ok || seen[obj] {
+				return true
+			}
+			if _, ok := obj.Type().(*types.TypeParam); ok {
+				return true
+			}
+			seen[obj] = true
+
+			ref := Reference{Name: obj.Name()}
+			if obj.Pkg() != nil {
+				ref.ImportPath = obj.Pkg().Path()
+				ref.ID = obj.Name()
+				if obj.Pkg() != b.pkg {
+					ref.Name = obj.Pkg().Name() + "." + obj.Name()
+				}
+			}
+			if b.opts.TypeURL != nil {
+				ref.URL = b.opts.TypeURL(obj)
+			}
+			refs = append(refs, ref)
+			return true
+		})
+	}
+	return refs
+}
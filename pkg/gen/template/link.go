@@ -0,0 +1,123 @@
+package template
+
+import (
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"html"
+	"strings"
+)
+
+// linkedCode renders src, the printed form of nodes, as an HTML block where
+// the identifiers referring to types and packages link to their documentation.
+func linkedCode(src string, nodes []ast.Node, info *types.Info, typeURL func(obj types.Object) string) string {
+	return "<pre>" + linkHTML(src, nodes, info, typeURL) + "</pre>\n"
+}
+
+// linkHTML escapes src, the printed form of nodes, linking the identifiers
+// referring to types and packages to their documentation.
+func linkHTML(src string, nodes []ast.Node, info *types.Info, typeURL func(obj types.Object) string) string {
+	return codeHTML(src, nodes, info, typeURL, false)
+}
+
+// highlightClasses are the classes of the spans wrapping the tokens of
+// highlighted code.
+var highlightClasses = map[token.Token]string{
+	token.COMMENT: "com",
+	token.STRING:  "str",
+	token.CHAR:    "str",
+	token.INT:     "num",
+	token.FLOAT:   "num",
+	token.IMAG:    "num",
+}
+
+// codeHTML escapes src, the printed form of nodes, linking the identifiers
+// referring to types and packages to their documentation when info and
+// typeURL are set. When highlight is set, keywords, literals and comments are
+// wrapped in spans whose class gives their kind.
+func codeHTML(src string, nodes []ast.Node, info *types.Info, typeURL func(obj types.Object) string, highlight bool) string {
+	// Identifiers are printed in the same order they appear in the nodes.
+	var idents []*ast.Ident
+	if info != nil && typeURL != nil {
+		for _, n := range nodes {
+			ast.Inspect(n, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					idents = append(idents, id)
+				}
+				return true
+			})
+		}
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		var open, end, text string
+		switch {
+		case tok == token.IDENT:
+			// Printed identifiers missing from the nodes, such as the receiver
+			// name made up by funcSignature, are left as they are.
+			i := 0
+			for i < len(idents) && idents[i].Name != lit {
+				i++
+			}
+			if i == len(idents) {
+				continue
+			}
+			id := idents[i]
+			idents = idents[i+1:]
+
+			url := identURL(id, info, typeURL)
+			if url == "" {
+				continue
+			}
+			open, end, text = `<a href="`+html.EscapeString(url)+`">`, "</a>", lit
+		case !highlight:
+			continue
+		case tok.IsKeyword():
+			open, end, text = `<span class="kw">`, "</span>", tok.String()
+		case highlightClasses[tok] != "":
+			open, end, text = `<span class="`+highlightClasses[tok]+`">`, "</span>", lit
+		default:
+			continue
+		}
+
+		offset := file.Offset(pos)
+		if offset+len(text) > len(src) || src[offset:offset+len(text)] != text {
+			// Literals such as raw strings with carriage returns are
+			// normalized by the scanner.
+			continue
+		}
+		b.WriteString(html.EscapeString(src[last:offset]))
+		b.WriteString(open + html.EscapeString(text) + end)
+		last = offset + len(text)
+	}
+	b.WriteString(html.EscapeString(src[last:]))
+	return b.String()
+}
+
+// identURL returns the URL of the type or package an identifier refers to.
+func identURL(id *ast.Ident, info *types.Info, typeURL func(obj types.Object) string) string {
+	switch obj := info.Uses[id].(type) {
+	case *types.PkgName:
+		return typeURL(obj)
+	case *types.TypeName:
+		if _, ok := obj.Type().(*types.TypeParam); ok {
+			return ""
+		}
+		return typeURL(obj)
+	default:
+		return ""
+	}
+}
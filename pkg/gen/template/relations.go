@@ -0,0 +1,54 @@
+package template
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/types"
+	"slices"
+)
+
+// typeName returns the object of the documented type t.
+func typeName(info *types.Info, t *doc.Type) *types.TypeName {
+	if info == nil || t.Decl == nil || len(t.Decl.Specs) == 0 {
+		return nil
+	}
+	spec, ok := t.Decl.Specs[0].(*ast.TypeSpec)
+	if !ok {
+		return nil
+	}
+	obj, _ := info.Defs[spec.Name].(*types.TypeName)
+	return obj
+}
+
+// promotedMethods returns the methods promoted to t that aren't documented
+// along with its own methods, as the embedded ones are when all the methods
+// are documented.
+func promotedMethods(info *types.Info, promoted func(obj *types.TypeName) []PromotedMethod, t *doc.Type) []PromotedMethod {
+	if promoted == nil {
+		return nil
+	}
+	obj := typeName(info, t)
+	if obj == nil {
+		return nil
+	}
+
+	var methods []PromotedMethod
+	for _, m := range promoted(obj) {
+		if !slices.ContainsFunc(t.Methods, func(f *doc.Func) bool { return f.Name == m.Name }) {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// relations returns the types related to t by rel, if any.
+func relations(info *types.Info, rel func(obj *types.TypeName) []Relation, t *doc.Type) []Relation {
+	if rel == nil {
+		return nil
+	}
+	obj := typeName(info, t)
+	if obj == nil {
+		return nil
+	}
+	return rel(obj)
+}
@@ -7,12 +7,15 @@ import (
 	"go/doc"
 	"go/printer"
 	"go/token"
+	"go/types"
 	"io"
+	"io/fs"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/charmbracelet/log"
 
@@ -48,50 +51,344 @@ func (e *multiNewLineEliminator) Write(in []byte) (int, error) {
 	return e.w.Write(out[:n])
 }
 
+// subPkgEntry is a package listed in a sub-packages tree.
+type subPkgEntry struct {
+	// Depth of the package in the tree, top level packages have depth 0.
+	Depth int
+	// Name is the path of the package relative to its parent in the tree.
+	Name string
+	// Link is the path to the package docs relative to the document listing the
+	// tree, empty when the package has no documentation file.
+	Link string
+	Pkg  *common.Pkg
+}
+
+// subPkgTree flattens the sub-packages hierarchy below base in depth-first order.
+func subPkgTree(base string, pkgs []*common.Pkg) []subPkgEntry {
+	var entries []subPkgEntry
+	var walk func(parent string, pkgs []*common.Pkg, depth int)
+	walk = func(parent string, pkgs []*common.Pkg, depth int) {
+		for _, p := range pkgs {
+			var link string
+			if p.DocFile != "" {
+				link = relPath(base, p.Path) + "/" + p.DocFile
+			}
+			entries = append(entries, subPkgEntry{
+				Depth: depth,
+				Name:  relPath(parent, p.Path),
+				Link:  link,
+				Pkg:   p,
+			})
+			walk(p.Path, p.SubPkgs, depth+1)
+		}
+	}
+	walk(base, pkgs, 0)
+	return entries
+}
+
+// relPath returns the slash separated path p relative to its ancestor base.
+func relPath(base, p string) string {
+	if base == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, base+"/")
+}
+
 // SummaryData is used to store the data for the summary template.
 type SummaryData struct {
+	// Root is the package of the root directory, documented by the summary
+	// with the main.md.gotmpl template. It's nil when there is none.
+	Root    *common.Pkg
 	SubPkgs []*common.Pkg
+	// Deprecated lists the deprecated packages and symbols of the project.
+	Deprecated []DeprecatedAPI
+}
+
+// DeprecatedAPI is a deprecated package or symbol listed in the summary.
+type DeprecatedAPI struct {
+	// Name of the symbol qualified with its package name, or the path of the package.
+	Name string
+	// Kind is one of common.Kinds.
+	Kind string
+	// Link to its documentation relative to the summary, empty when it isn't
+	// documented, such as the hidden ones.
+	Link string
+	// Replacement is its "Deprecated: " paragraph, which usually names what to
+	// use instead, rendered as markdown for a table cell.
+	Replacement string
+}
+
+// Section is a section of the documentation of a package, rendered on its own
+// to be injected into an existing file.
+type Section struct {
+	Pkg *common.Pkg
+	// Name of the section, one of SectionNames.
+	Name string
+}
+
+// sections holds the templates rendering each section of a package, they
+// are executed with the *common.Pkg.
+var sections = map[string]string{
+	"doc":          `{{ with deprecation .Package.Doc }}{{ template "deprecated" . }}{{ end }}{{ doc .Package.Doc }}`,
+	"index":        `{{ template "index" . }}`,
+	"subpackages":  `{{ template "subpackages" . }}`,
+	"examples":     `{{ template "examples" .Package.Examples }}`,
+	"constants":    `{{ template "consts" .Package.Consts }}`,
+	"variables":    `{{ template "vars" .Package.Vars }}`,
+	"functions":    `{{ template "functions" .Package }}`,
+	"types":        `{{ template "types" .Package }}`,
+	"undocumented": `{{ template "undocumented" . }}`,
+}
+
+// SectionNames returns the names of the sections that can be rendered on their own.
+func SectionNames() []string {
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SingleFileData is used to store the data for the api.md.gotmpl template,
+// documenting every package in a single file.
+type SingleFileData struct {
+	Title string
+	// Root is the package of the root directory, nil when there is none.
+	Root *common.Pkg
+	// SubPkgs are the top level packages of the tree, below the root one.
+	SubPkgs []*common.Pkg
+	// Sections holds the rendered documentation of each package.
+	Sections map[*common.Pkg]string
 }
 
 //go:embed *.md.gotmpl
 var files embed.FS
 
+// Options customize the execution of the templates.
+type Options struct {
+	// Custom holds *.gotmpl files overriding the embedded templates: a file
+	// replaces the embedded file with the same name, and a {{ define }} block
+	// replaces the embedded block with the same name, even when it is empty.
+	Custom fs.FS
+	// Markdown holds the options used to render doc comments.
+	Markdown []markdown.Option
+	// SingleFile renders the package as a section of a document holding every
+	// package, its anchors are qualified with its import path like
+	// common.AnchorID, and the sub-packages link to their sections.
+	SingleFile bool
+	// HideDeprecated omits the deprecated fields from the field tables, the
+	// deprecated symbols are removed from the documentation beforehand.
+	HideDeprecated bool
+	// SourceDir is the directory of the sources relative to the
+	// documentation, empty when they're in the same directory.
+	SourceDir string
+	// TypeURL returns the URL of the documentation of a type or package
+	// referenced in a declaration, or an empty string when it has none. When set,
+	// declarations are rendered as HTML blocks where those references are links.
+	TypeURL func(obj types.Object) string
+	// Implements returns the interfaces implemented by a type, and
+	// ImplementedBy the types implementing an interface. When set, type
+	// sections list them.
+	Implements    func(obj *types.TypeName) []Relation
+	ImplementedBy func(obj *types.TypeName) []Relation
+	// PromotedMethods returns the methods a type gains through its embedded
+	// fields. When set, type sections list them.
+	PromotedMethods func(obj *types.TypeName) []PromotedMethod
+}
+
+// PromotedMethod is a method promoted from an embedded field.
+type PromotedMethod struct {
+	Name string
+	// Signature of the method without the func keyword and the receiver.
+	Signature string
+	// From is the type declaring the method, qualified with its package name
+	// when it's declared in another package. Generic types are given with the
+	// type arguments of the embedded instance.
+	From string
+	// URL of the documentation of the method, empty when it has none.
+	URL string
+	// Pointer reports whether the method is promoted only to the pointer to the type.
+	Pointer bool
+}
+
+// Relation is a type related to a documented type, such as an interface it implements.
+type Relation struct {
+	// Name of the type, qualified with its package name when it's declared in
+	// another package.
+	Name string
+	// URL of the documentation of the type, empty when it has none.
+	URL string
+	// Pointer reports whether the interface is implemented only by the
+	// pointer to the concrete type.
+	Pointer bool
+}
+
 // Execute is used to execute the README.md template.
-func Execute(w io.Writer, data interface{}, cfg interface{}, options ...markdown.Option) error {
+func Execute(w io.Writer, data interface{}, cfg interface{}, opts Options) error {
 	switch v := data.(type) {
 	case *common.Pkg:
-		templates, err := template.New("main.md.gotmpl").Funcs(funcs(cfg, v.FilesSet, options)).ParseFS(files, "*")
+		templates, err := parseTemplates("main.md.gotmpl", funcs(cfg, v, opts), opts.Custom, "*")
 		if err != nil {
 			return err
 		}
 		return templates.Execute(&multiNewLineEliminator{w: w}, data)
 	case *SummaryData:
-		templates, err := template.New("summary.md.gotmpl").Funcs(funcs(cfg, nil, options)).ParseFS(files, "summary.md.gotmpl")
+		templates, err := parseTemplates("summary.md.gotmpl", funcs(cfg, v.Root, opts), opts.Custom, "*")
+		if err != nil {
+			return err
+		}
+		return templates.Execute(&multiNewLineEliminator{w: w}, v)
+	case *Section:
+		src, ok := sections[v.Name]
+		if !ok {
+			return fmt.Errorf("unknown section %q, expected one of %s", v.Name, strings.Join(SectionNames(), ", "))
+		}
+		templates, err := parseTemplates("main.md.gotmpl", funcs(cfg, v.Pkg, opts), opts.Custom, "*")
+		if err != nil {
+			return err
+		}
+		section, err := templates.New("dors:section").Parse(src)
+		if err != nil {
+			return err
+		}
+		return section.Execute(&multiNewLineEliminator{w: w}, v.Pkg)
+	case *SingleFileData:
+		templates, err := parseTemplates("api.md.gotmpl", funcs(cfg, nil, opts), opts.Custom, "api.md.gotmpl")
+		if err != nil {
+			return err
+		}
+		return templates.Execute(&multiNewLineEliminator{w: w}, v)
+	case *Page:
+		templates, err := parseHTMLTemplates("page.html.gotmpl", htmlFuncs(cfg, v.Pkg, opts), opts.Custom)
 		if err != nil {
 			return err
 		}
 		return templates.Execute(&multiNewLineEliminator{w: w}, v)
 	default:
-		return fmt.Errorf("invalid data type, expected *doc.Package or *SummaryData got %T", data)
+		return fmt.Errorf("invalid data type, expected *doc.Package, *Section, *SummaryData, *SingleFileData or *Page got %T", data)
 	}
 }
 
-func funcs(cfg interface{}, set *token.FileSet, options []markdown.Option) template.FuncMap {
+// parseTemplates parses the embedded templates matching patterns and applies the
+// overrides in custom, returning the template called name. The *.html.gotmpl
+// files of the HTML site and the markdown ones are separate sets.
+func parseTemplates(name string, funcMap template.FuncMap, custom fs.FS, patterns ...string) (*template.Template, error) {
+	trees, err := parseTrees(name, funcMap, custom, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Templates are added to a new set, adding an empty tree to a set that
+	// already holds the name would keep the previous definition.
+	templates := template.New(name).Funcs(funcMap)
+	for n, tree := range trees {
+		if _, err := templates.AddParseTree(n, tree); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// parseTrees parses the embedded templates matching patterns and the ones in
+// custom, returning the trees of the templates by name.
+func parseTrees(name string, funcMap template.FuncMap, custom fs.FS, patterns ...string) (map[string]*parse.Tree, error) {
+	isHTML := strings.HasSuffix(name, ".html.gotmpl")
+	embeddedFS := fs.FS(files)
+	if isHTML {
+		embeddedFS = htmlFiles
+	}
+	embedded, err := template.New(name).Funcs(funcMap).ParseFS(embeddedFS, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	trees := make(map[string]*parse.Tree)
+	for _, t := range embedded.Templates() {
+		if t.Tree != nil {
+			trees[t.Name()] = t.Tree
+		}
+	}
+	if custom == nil {
+		return trees, nil
+	}
+
+	matches, err := fs.Glob(custom, "*.gotmpl")
+	if err != nil {
+		return nil, err
+	}
+	var customFiles []string
+	for _, f := range matches {
+		if strings.HasSuffix(f, ".html.gotmpl") == isHTML {
+			customFiles = append(customFiles, f)
+		}
+	}
+	if len(customFiles) == 0 {
+		return trees, nil
+	}
+
+	overrides, err := template.New(name).Funcs(funcMap).ParseFS(custom, customFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing custom templates: %w", err)
+	}
+	for _, t := range overrides.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		// Files holding only definitions keep the embedded file of the same name.
+		if slices.Contains(customFiles, t.Name()) && parse.IsEmptyTree(t.Tree.Root) {
+			continue
+		}
+		trees[t.Name()] = t.Tree
+	}
+	return trees, nil
+}
+
+// funcs returns the template functions, pkg is nil for the documents that
+// don't document a package.
+func funcs(cfg interface{}, pkg *common.Pkg, opts Options) template.FuncMap {
+	var (
+		set    *token.FileSet
+		info   *types.Info
+		strs   map[types.Object]string
+		fields map[types.Object]*ast.Field
+	)
+	if pkg != nil {
+		set, info, strs, fields = pkg.FilesSet, pkg.TypesInfo, pkg.Strings, pkg.Fields
+	}
+
 	return template.FuncMap{
 		"config": func() interface{} {
 			return cfg
 		},
 		"doc": func(s string) string {
 			b := &strings.Builder{}
-			markdown.ToMarkdown(b, s, options...)
+			markdown.ToMarkdown(b, s, opts.Markdown...)
 			return b.String()
 		},
+		"inlineDoc": func(s string) string {
+			b := &strings.Builder{}
+			markdown.ToMarkdown(b, s, opts.Markdown...)
+			return strings.Join(strings.Fields(b.String()), " ")
+		},
+		"deprecation": func(doc string) string {
+			text, _ := common.Deprecation(doc)
+			return text
+		},
+		"tableCell": tableCell,
+		"undocumented": func(p *common.Pkg) []common.Symbol {
+			var syms []common.Symbol
+			for _, sym := range p.ExportedSymbols() {
+				if strings.TrimSpace(sym.Doc) == "" {
+					syms = append(syms, sym)
+				}
+			}
+			return syms
+		},
 		"hasSection": func(sections []string, section string) bool {
 			return slices.Contains(sections, section)
 		},
-		"gocode": func(s string) string {
-			return "```go\n" + s + "\n```\n"
-		},
+		"gocode": gocode,
 		"code": func(s string) string {
 			if !strings.HasSuffix(s, "\n") {
 				s = s + "\n"
@@ -106,11 +403,7 @@ func funcs(cfg interface{}, set *token.FileSet, options []markdown.Option) templ
 			s = r.ReplaceAllString(s, "{ ... }")
 			return "`" + s + "`"
 		},
-		"gocodeEllipsis": func(s string) string {
-			r := regexp.MustCompile(`{(?s).*}`)
-			s = r.ReplaceAllString(s, "{ ... }")
-			return "```go\n" + s + "\n```\n"
-		},
+		"gocodeEllipsis": gocodeEllipsis,
 		"importPath": func(p *doc.Package) string {
 			return p.ImportPath
 		},
@@ -118,7 +411,11 @@ func funcs(cfg interface{}, set *token.FileSet, options []markdown.Option) templ
 			return strings.TrimPrefix(p.ImportPath, "github.com/")
 		},
 		"filename": func(pos token.Pos) string {
-			return filename(set, pos)
+			name := filename(set, pos)
+			if name == "" || opts.SourceDir == "" {
+				return name
+			}
+			return opts.SourceDir + "/" + name
 		},
 		"lineNumber": func(pos token.Pos) int {
 			return lineNumber(set, pos)
@@ -126,15 +423,116 @@ func funcs(cfg interface{}, set *token.FileSet, options []markdown.Option) templ
 		"funcSignature": func(decl *ast.FuncDecl) string {
 			return funcSignature(set, decl)
 		},
-		"fmtDeclaration": func(decl *ast.GenDecl, spec ast.Spec) string {
-			return fmtDeclaration(set, decl, spec)
+		"fmtDeclaration": func(decl *ast.GenDecl, specs ...ast.Spec) string {
+			return fmtDeclaration(set, decl, specs...)
+		},
+		"funcBlock": func(decl *ast.FuncDecl) string {
+			if opts.TypeURL == nil || info == nil {
+				return gocodeEllipsis(funcSignature(set, decl))
+			}
+			nodes := []ast.Node{decl.Name, decl.Type}
+			if decl.Recv != nil {
+				nodes = append([]ast.Node{decl.Recv}, nodes...)
+			}
+			return linkedCode(funcSignature(set, decl), nodes, info, opts.TypeURL)
+		},
+		"typeParams": func(node interface{}) []typeParam {
+			return typeParams(set, info, opts.TypeURL, node)
+		},
+		"declBlock": func(decl *ast.GenDecl, specs ...ast.Spec) string {
+			if opts.TypeURL == nil || info == nil {
+				return gocode(fmtDeclaration(set, decl, specs...))
+			}
+			if len(specs) == 0 {
+				specs = decl.Specs
+			}
+			nodes := make([]ast.Node, len(specs))
+			for i, spec := range specs {
+				nodes[i] = spec
+			}
+			return linkedCode(fmtDeclaration(set, decl, specs...), nodes, info, opts.TypeURL)
+		},
+		"valueRows": func(decl *ast.GenDecl) []valueRow {
+			return valueRows(set, info, decl)
+		},
+		"enumValues": func(t *doc.Type) *enum {
+			return enumValues(info, strs, t)
 		},
+		"implements": func(t *doc.Type) []Relation {
+			return relations(info, opts.Implements, t)
+		},
+		"implementedBy": func(t *doc.Type) []Relation {
+			return relations(info, opts.ImplementedBy, t)
+		},
+		"promotedMethods": func(t *doc.Type) []PromotedMethod {
+			return promotedMethods(info, opts.PromotedMethods, t)
+		},
+		"structFields": func(t *doc.Type) *fieldTable {
+			return structFields(set, info, fields, opts.TypeURL, opts.HideDeprecated, t)
+		},
+		"docHTML": func(s string) string {
+			return docHTML(s, opts.Markdown)
+		},
+		"inlineDocHTML": func(s string) string {
+			return inlineDocHTML(s, opts.Markdown)
+		},
+		"declHTML": func(decl *ast.GenDecl, specs ...ast.Spec) string {
+			return declHTML(set, info, opts.TypeURL, decl, specs...)
+		},
+		"funcHTML": func(decl *ast.FuncDecl) string {
+			return funcHTML(set, info, opts.TypeURL, decl)
+		},
+		"gocodeHTML": func(s string) string {
+			return highlightedCode(s, nil, nil, nil)
+		},
+		"subPkgTree": func(base string, pkgs []*common.Pkg) []subPkgEntry {
+			entries := subPkgTree(base, pkgs)
+			if opts.SingleFile {
+				for i := range entries {
+					entries[i].Link = "#" + common.AnchorID(entries[i].Pkg, "", "")
+				}
+			}
+			return entries
+		},
+		"indent": func(depth int) string {
+			return strings.Repeat("  ", depth)
+		},
+		"anchor": func(id string) string {
+			if opts.SingleFile && pkg != nil {
+				id = common.AnchorID(pkg, "", id)
+			}
+			return `<a id="` + id + `"></a>`
+		},
+		"anchorURL": func(id string) string {
+			if opts.SingleFile && pkg != nil {
+				id = common.AnchorID(pkg, "", id)
+			}
+			return "#" + id
+		},
+		"join": strings.Join,
+		"shortSignature": func(decl *ast.FuncDecl) string {
+			return strings.Join(strings.Fields(funcSignature(set, decl)), " ")
+		},
+		"symbolID": common.SymbolID,
 		"basename": func(p string) string {
 			return filepath.Base(p)
 		},
+		"exampleCode": func(ex *doc.Example) string {
+			return exampleCode(set, ex)
+		},
 	}
 }
 
+func gocode(s string) string {
+	return "```go\n" + s + "\n```\n"
+}
+
+func gocodeEllipsis(s string) string {
+	r := regexp.MustCompile(`{(?s).*}`)
+	s = r.ReplaceAllString(s, "{ ... }")
+	return "```go\n" + s + "\n```\n"
+}
+
 func filename(fset *token.FileSet, pos token.Pos) string {
 	if pos == token.NoPos {
 		return ""
@@ -168,23 +566,11 @@ func funcSignature(fset *token.FileSet, decl *ast.FuncDecl) string {
 	var sig2 strings.Builder
 	sig2.WriteString("func ")
 	if decl.Recv != nil && len(decl.Recv.List) > 0 {
-		var ptr, declType string
-
-		switch recvType := decl.Recv.List[0].Type.(type) {
-		case *ast.StarExpr:
-			// Receiver is a pointer type
-			ptr = "*"
-			if ident, ok := recvType.X.(*ast.Ident); ok {
-				declType = ident.Name
-			} else {
-				declType = "unknown"
-			}
-		case *ast.Ident:
-			// Receiver is a non-pointer type
-			declType = recvType.Name
-		default:
-			// Receiver is of an unexpected type
-			declType = "unknown"
+		// Receiver type, including the pointer and the type parameters of
+		// generic types such as *Map[K, V]
+		var recvType strings.Builder
+		if err := printer.Fprint(&recvType, fset, decl.Recv.List[0].Type); err != nil {
+			return ""
 		}
 
 		// Extract receiver name
@@ -194,7 +580,7 @@ func funcSignature(fset *token.FileSet, decl *ast.FuncDecl) string {
 		}
 
 		// Construct receiver string
-		receiver := fmt.Sprintf("(%s %s%s)", receiverName, ptr, declType)
+		receiver := fmt.Sprintf("(%s %s)", receiverName, recvType.String())
 		sig2.WriteString(receiver)
 		sig2.WriteString(" ")
 	}
@@ -207,7 +593,146 @@ func funcSignature(fset *token.FileSet, decl *ast.FuncDecl) string {
 	return sig2.String()
 }
 
-func fmtDeclaration(fset *token.FileSet, decl *ast.GenDecl, spec ast.Spec) string {
+// typeParam is a type parameter of a generic type or function.
+type typeParam struct {
+	Name string
+	// Constraint is the markdown of the constraint, with its types linked when
+	// declarations are rendered as HTML.
+	Constraint string
+}
+
+// typeParams returns the type parameters of a *ast.FuncDecl, or of the type
+// declared by a *ast.GenDecl.
+func typeParams(fset *token.FileSet, info *types.Info, typeURL func(obj types.Object) string, node interface{}) []typeParam {
+	var fields *ast.FieldList
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		if n.Type != nil {
+			fields = n.Type.TypeParams
+		}
+	case *ast.GenDecl:
+		if len(n.Specs) > 0 {
+			if spec, ok := n.Specs[0].(*ast.TypeSpec); ok {
+				fields = spec.TypeParams
+			}
+		}
+	}
+	if fields == nil {
+		return nil
+	}
+
+	var params []typeParam
+	for _, field := range fields.List {
+		var constraint strings.Builder
+		if err := printer.Fprint(&constraint, fset, field.Type); err != nil {
+			log.Errorf("Error printing type parameter constraint: %v", err)
+			continue
+		}
+
+		cell := "`" + constraint.String() + "`"
+		if typeURL != nil && info != nil {
+			cell = "<code>" + linkHTML(constraint.String(), []ast.Node{field.Type}, info, typeURL) + "</code>"
+		}
+		// Unions such as ~int | ~float64 would split the table cell.
+		cell = tableCell(cell)
+
+		for _, name := range field.Names {
+			params = append(params, typeParam{Name: name.Name, Constraint: cell})
+		}
+	}
+	return params
+}
+
+// valueRow is a constant or variable of a group rendered as a table.
+type valueRow struct {
+	Name  string
+	Value string
+	Doc   string
+}
+
+// valueRows returns a row for every name declared by a const or var group.
+// Constant values are computed, so iota and implicit repetitions are resolved.
+func valueRows(fset *token.FileSet, info *types.Info, decl *ast.GenDecl) []valueRow {
+	var rows []valueRow
+	for _, s := range decl.Specs {
+		spec, ok := s.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		text := specDoc(spec)
+		for i, name := range spec.Names {
+			row := valueRow{Name: name.Name, Doc: text}
+			var c *types.Const
+			if info != nil {
+				c, _ = info.Defs[name].(*types.Const)
+			}
+			if c != nil {
+				row.Value = c.Val().ExactString()
+			} else if i < len(spec.Values) {
+				var value strings.Builder
+				if err := printer.Fprint(&value, fset, spec.Values[i]); err != nil {
+					log.Errorf("Error printing value: %v", err)
+				}
+				row.Value = value.String()
+			}
+			if row.Value != "" {
+				row.Value = "`" + tableCell(row.Value) + "`"
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// specDoc returns the doc or the line comment of a spec as a table cell.
+func specDoc(spec *ast.ValueSpec) string {
+	text := spec.Doc.Text()
+	if text == "" {
+		text = spec.Comment.Text()
+	}
+	return tableCell(strings.Join(strings.Fields(text), " "))
+}
+
+// tableCell escapes the characters of s that would break a table cell.
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+var exampleOutputRx = regexp.MustCompile(`(?i)//[[:space:]]*(unordered )?output:`)
+
+// exampleCode returns the source of an example, preferring the runnable
+// program when go/doc was able to synthesize one.
+func exampleCode(fset *token.FileSet, ex *doc.Example) string {
+	var code strings.Builder
+	if ex.Play != nil {
+		if err := printer.Fprint(&code, fset, ex.Play); err != nil {
+			log.Errorf("Error printing example %s: %v", ex.Name, err)
+			return ""
+		}
+		return strings.TrimSpace(code.String())
+	}
+
+	node := &printer.CommentedNode{Node: ex.Code, Comments: ex.Comments}
+	if err := printer.Fprint(&code, fset, node); err != nil {
+		log.Errorf("Error printing example %s: %v", ex.Name, err)
+		return ""
+	}
+
+	s := code.String()
+	// Strip the braces of the function body and unindent it.
+	if n := len(s); n >= 2 && s[0] == '{' && s[n-1] == '}' {
+		s = strings.ReplaceAll(s[1:n-1], "\n\t", "\n")
+	}
+	// The expected output is rendered separately.
+	if loc := exampleOutputRx.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]]
+	}
+	return strings.TrimSpace(s)
+}
+
+func fmtDeclaration(fset *token.FileSet, decl *ast.GenDecl, specs ...ast.Spec) string {
 	if decl == nil {
 		return ""
 	}
@@ -215,9 +740,26 @@ func fmtDeclaration(fset *token.FileSet, decl *ast.GenDecl, spec ast.Spec) strin
 	var sig strings.Builder
 
 	genDel := *decl
-	genDel.Specs = []ast.Spec{spec}
+	genDel.Doc = nil
+	if len(specs) > 0 {
+		genDel.Specs = specs
+	}
+
+	// Unexported specs are filtered out of the declaration, don't leave a
+	// blank line where they were.
+	if genDel.Rparen.IsValid() && len(genDel.Specs) > 0 {
+		last := genDel.Specs[len(genDel.Specs)-1]
+		genDel.Rparen = last.End()
+		if spec, ok := last.(*ast.ValueSpec); ok && spec.Comment != nil {
+			genDel.Rparen = spec.Comment.End()
+		}
+	}
 
-	err := printer.Fprint(&sig, fset, &genDel)
+	// Align the groups with spaces, like gofmt does. The doc of the
+	// declaration is rendered on its own, the comments of its specs and
+	// fields are kept.
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	err := cfg.Fprint(&sig, fset, &genDel)
 	if err != nil {
 		log.Errorf("Error printing type declaration: %v", err)
 		return ""
@@ -0,0 +1,98 @@
+// Package generics declares generic types and functions.
+package generics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number is a constraint satisfied by the numeric types.
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// List is a list of values of any type.
+type List[T any] struct {
+	items []T
+}
+
+// NewList returns a list holding values.
+func NewList[T any](values ...T) *List[T] {
+	return &List[T]{items: values}
+}
+
+// Push appends v to the list.
+func (l *List[T]) Push(v T) {
+	l.items = append(l.items, v)
+}
+
+// Len returns the number of values in the list.
+func (l List[T]) Len() int {
+	return len(l.items)
+}
+
+// Map is a map with ordered keys.
+type Map[K comparable, V any] struct {
+	keys   []K
+	values map[K]V
+}
+
+// Get returns the value of k, and whether it is set.
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	v, ok := m.values[k]
+	return v, ok
+}
+
+// Keys returns the keys of m in insertion order.
+func (m *Map[_, _]) Keys() []string {
+	return nil
+}
+
+// Pair holds two values of possibly different types.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Registry holds instantiated generic types.
+type Registry struct {
+	// Names lists the registered names.
+	Names List[string]
+	// Counts maps the names to their count.
+	Counts *Map[string, int]
+}
+
+// IntList is an alias of an instantiated list.
+type IntList = List[int]
+
+// Sum returns the sum of values.
+func Sum[T Number](values ...T) T {
+	var sum T
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// Keys returns the keys of m.
+func Keys[M ~map[K]V, K comparable, V any](m M) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Join joins the string forms of values.
+func Join[T fmt.Stringer](values []T, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// Swap returns a pair with the values of p swapped.
+func Swap[A, B any](p Pair[A, B]) Pair[B, A] {
+	return Pair[B, A]{First: p.Second, Second: p.First}
+}
@@ -0,0 +1,15 @@
+// Package links is the root package, documented by the summary. See [Root.Name].
+package links
+
+// Root is declared in the root package.
+type Root struct {
+	name string
+}
+
+// Name returns the name of r.
+func (r Root) Name() string {
+	return r.name
+}
+
+// Deprecated: Use [Root] instead.
+type Old = Root
@@ -0,0 +1,21 @@
+// Package sub refers to the root package in [links.Root] and to the standard
+// library in [fmt.Stringer].
+package sub
+
+import (
+	"fmt"
+
+	"example.com/links"
+)
+
+// Wrap returns the root of s, see [Root].
+func Wrap(s fmt.Stringer) links.Root {
+	return links.Root{}
+}
+
+// Root holds a [links.Root].
+type Root struct {
+	links.Root
+	// Parent is the parent of the root.
+	Parent *Root
+}
@@ -0,0 +1,54 @@
+// Package promoted embeds types whose methods have value and pointer
+// receivers, unexported types and generic types.
+package promoted
+
+// Base has methods with value and pointer receivers.
+type Base struct{}
+
+// Get has a value receiver.
+func (Base) Get() string { return "" }
+
+// Set has a pointer receiver.
+func (*Base) Set(string) {}
+
+// hidden is unexported, its methods aren't documented.
+type hidden struct{}
+
+// Hidden is promoted from an unexported type.
+func (hidden) Hidden() {}
+
+// Box holds a value of any type.
+type Box[T any] struct {
+	v T
+}
+
+// Put stores v.
+func (b *Box[T]) Put(v T) { b.v = v }
+
+// Value returns the stored value.
+func (b Box[T]) Value() T { return b.v }
+
+// ByValue embeds its types by value, only the methods with a value receiver
+// are in the method set of its values.
+type ByValue struct {
+	Base
+	hidden
+	Box[int]
+}
+
+// ByPointer embeds a pointer, every method of Base is in the method set of
+// its values.
+type ByPointer struct {
+	*Base
+}
+
+// Getter gets.
+type Getter interface {
+	Get() string
+}
+
+// GetSetter embeds an interface, whose methods are its own.
+type GetSetter interface {
+	Getter
+	Set(string)
+}
@@ -0,0 +1,11 @@
+// Package sub embeds the types of another package.
+package sub
+
+import "example.com/promoted"
+
+// Outer embeds a type of the parent package and an instance of its generic
+// type.
+type Outer struct {
+	promoted.ByValue
+	*promoted.Box[string]
+}
@@ -1,18 +1,23 @@
 package gen
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"go/doc"
+	"go/doc/comment"
+	"go/types"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 
-	"github.com/spf13/cobra"
-
 	"github.com/charmbracelet/log"
 	"github.com/ulm0/dors/pkg/common"
+	"github.com/ulm0/dors/pkg/gen/markdown"
 	"github.com/ulm0/dors/pkg/gen/template"
 )
 
@@ -50,11 +55,150 @@ type Config struct {
 	SkipSubPkgs bool `json:"skipSubPkgs"`
 	// SkipExamples will omit the examples from the README.
 	SkipExamples bool `json:"skipExamples"`
+	// Render doc comments with the legacy parser instead of the Go 1.19 doc
+	// comment syntax, which supports lists, headings and doc links.
+	LegacyMarkdown bool `json:"legacyMarkdown"`
+	// Render declarations as HTML blocks where the referenced types link to their documentation.
+	LinkTypes bool `json:"linkTypes"`
+	// Directory with *.gotmpl files overriding the built-in templates, relative
+	// to the root directory. A file replaces the built-in file with the same name,
+	// and a {{ define }} block replaces the built-in block with the same name.
+	TemplateDir string `json:"templateDir"`
+	// Render grouped constants and variables as a table with the name, value
+	// and description of each of them, instead of their declaration.
+	ValueTables bool `json:"valueTables"`
+	// Render a table with the exported fields of struct types, including the
+	// ones promoted from embedded structs, with their type, tags, default value
+	// and doc. A line of the field doc starting with "Default:" gives its default value.
+	FieldTables bool `json:"fieldTables"`
+	// List the interfaces each type implements, and the types implementing each
+	// interface of the project.
+	Implements bool `json:"implements"`
+	// Interfaces from outside the project checked for the Implements sections,
+	// predeclared like "error" or qualified with their import path like "io.Reader".
+	// Generate fails when one of them can't be resolved.
+	Interfaces []string `json:"interfaces"`
+	// List the methods types gain through their embedded fields, linked to the
+	// documentation of the embedded type.
+	PromotedMethods bool `json:"promotedMethods"`
+	// Format of the documentation, FormatMarkdown, FormatJSON or FormatHTML.
+	// If empty markdown is generated. Field and value tables and enum values
+	// are only rendered in markdown.
+	Format string `json:"format"`
+	// Directory the documentation is written to, relative to the root
+	// directory, mirroring the package tree. If empty the documentation is
+	// written next to the sources, or to "site" for the HTML site.
+	OutDir string `json:"outDir"`
+	// Name of the documentation files, such as README.md or index.md. If empty
+	// DOCS.md is used, or DOCS.json and index.html for the other formats. With
+	// SingleFile it names the single file, API.md by default.
+	OutputName string `json:"outputName"`
+	// Render every package into a single markdown file with a table of
+	// contents, instead of a file for each package and a summary.
+	SingleFile bool `json:"singleFile"`
+	// Update the regions between <!-- dors:start --> and <!-- dors:end -->
+	// markers of existing files, README.md unless OutputName is set, instead of
+	// overwriting them. Files without markers are left alone.
+	Inject bool `json:"inject"`
+	// Render an Index section listing the symbols of the package with their
+	// signature, linked to their heading.
+	Index bool `json:"index"`
+	// Anchors selects how the headings of the symbols are identified in
+	// markdown: AnchorsHTML, the default, adds an anchor named after the symbol
+	// to each of them, the other styles rely on the IDs generated from the
+	// heading text by GitHub, GitLab or CommonMark renderers.
+	Anchors string `json:"anchors"`
+	// Omit the deprecated packages, symbols and struct fields, whose doc has a
+	// "Deprecated: " paragraph, from the documentation. The summary still
+	// lists them in its Deprecated APIs report.
+	HideDeprecated bool `json:"hideDeprecated"`
+	// DeprecationNotice selects how the deprecated packages and symbols are
+	// flagged: DeprecationAlert, the default, DeprecationQuote, or
+	// DeprecationNone to keep the "Deprecated: " paragraph in their doc.
+	DeprecationNotice string `json:"deprecationNotice"`
+	// List the exported symbols without a doc comment in an Undocumented
+	// section at the end of the documentation of each package.
+	Undocumented bool `json:"undocumented"`
+	// Minimum percentage of exported symbols with a doc comment, below which
+	// the coverage report fails.
+	CoverageThreshold float64 `json:"coverageThreshold"`
+	// Lint selects the rules of the doc comment linter.
+	Lint LintConfig `json:"lint"`
+	// Overrides change the configuration of the packages matching their path.
+	// Later overrides take precedence over earlier ones.
+	Overrides []Override `json:"overrides"`
+	// Path to the config file, if empty the config file is looked up in the root directory.
+	ConfigFile string `json:"-"`
+	// Check compares the generated documentation with the files on disk instead
+	// of writing it, reporting the stale files.
+	Check bool `json:"-"`
 }
 
 // Gen is used to generate documentation for a Go package.
 type Gen struct {
 	config Config
+	// pinned holds the settings given as flags, they take precedence over the config file.
+	pinned []byte
+	// rootDir is the directory being documented, set by Generate.
+	rootDir string
+	// pkgNames maps the names of the documented packages to their import path,
+	// names shared by several packages map to an empty string. Set by Generate.
+	pkgNames map[string]string
+	// pkgs holds the documented packages sorted by path. Set by Generate.
+	pkgs []*common.Pkg
+	// index holds the symbols documented in the project. Set by Generate.
+	index *common.Index
+	// deprecated holds the deprecated packages and symbols of the project,
+	// including the hidden ones. Set by Generate.
+	deprecated []deprecatedAPI
+	// impls relates the types of the project to the interfaces they
+	// implement. Set by Generate when the Implements sections are rendered.
+	impls *implementations
+}
+
+// Result reports the outcome of a documentation generation.
+type Result struct {
+	// Files lists the files written, relative to the root directory.
+	Files []string
+	// Stale lists the files that are out of date, only set when Config.Check is enabled.
+	Stale []StaleFile
+	// Errors holds the packages that failed to be documented, the generation
+	// continues with the rest of the packages.
+	Errors []*PackageError
+	// Unresolved lists the doc links that couldn't be resolved.
+	Unresolved []UnresolvedLink
+}
+
+// UnresolvedLink is a name in brackets, meant as a doc link to a symbol of the
+// package or of another package, that doesn't point to a known symbol.
+type UnresolvedLink struct {
+	// File is the documentation file containing the link, relative to the root directory.
+	File string
+	// Link is the text of the link.
+	Link string
+}
+
+// StaleFile is a documentation file that doesn't match the generated content.
+type StaleFile struct {
+	// Path of the file relative to the root directory.
+	Path string
+	// Diff is the unified diff between the file on disk and the generated content.
+	Diff string
+}
+
+// PackageError is an error that occurred while documenting a single package.
+type PackageError struct {
+	// Path of the package relative to the root directory.
+	Path string
+	Err  error
+}
+
+func (e *PackageError) Error() string {
+	return fmt.Sprintf("package %s: %v", e.Path, e.Err)
+}
+
+func (e *PackageError) Unwrap() error {
+	return e.Err
 }
 
 // New creates a new Gen instance.
@@ -62,31 +206,114 @@ func New(c Config) *Gen {
 	return &Gen{config: c}
 }
 
-// Run executes the documentation generation process.
-func (g *Gen) Run(cmd *cobra.Command, args []string) {
-	rootDir := getArgs(args)
+// Generate renders the documentation of every package under rootDir and writes
+// it next to the sources, or compares it with the files on disk when Config.Check
+// is enabled. The config file of the project is read on every call, so a Gen
+// can be reused for several directories.
+func (g *Gen) Generate(ctx context.Context, rootDir string) (*Result, error) {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path: %w", err)
+	}
 	log.Info("Starting documentation generation", "rootDir", rootDir)
 
-	pkgs, err := g.collectPkgs(rootDir)
+	run := &Gen{config: g.config.clone(), pinned: g.pinned, rootDir: rootDir}
+	if err := run.loadConfig(rootDir); err != nil {
+		return nil, err
+	}
+	if err := run.checkFormat(); err != nil {
+		return nil, err
+	}
+	if err := run.checkOutput(); err != nil {
+		return nil, err
+	}
+	if err := run.checkAnchors(); err != nil {
+		return nil, err
+	}
+	if err := run.checkDeprecationNotice(); err != nil {
+		return nil, err
+	}
+	if err := run.checkTemplateDirs(); err != nil {
+		return nil, err
+	}
+
+	pkgs, pkgErrs, err := run.collectPkgs(ctx, rootDir)
 	if err != nil {
-		log.Fatal("Failed to collect packages", "error", err)
+		return nil, fmt.Errorf("collecting packages: %w", err)
 	}
 
+	result := &Result{Errors: pkgErrs}
 	if len(pkgs) == 0 {
 		log.Info("No Go packages found in the specified directory. No documentation generated.", "rootDir", rootDir)
-		return
+		return result, nil
+	}
+
+	run.pkgs = pkgs
+	run.pkgNames = pkgNames(pkgs)
+	run.index = common.NewIndex(pkgs)
+	run.index.SetSingleFile(run.config.SingleFile)
+	run.index.SetPkgGoDevLinks(run.config.Format == FormatJSON)
+	if run.usesImplementations() {
+		ifaces, err := run.loadInterfaces(ctx, rootDir, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		run.impls = newImplementations(pkgs, ifaces)
 	}
 
-	hasRootGoFiles := g.hasGoFilesInRoot(pkgs)
+	hasRootGoFiles := run.hasGoFilesInRoot(pkgs)
 	log.Info("Root has Go files", "hasRootGoFiles", hasRootGoFiles)
 
-	// Generate per-package DOCS.md files
-	log.Info("Generating per-package DOCS.md files")
-	g.generatePerPkgReadme(pkgs, rootDir, g.config)
+	// Render per-package DOCS.md files
+	log.Info("Rendering per-package DOCS.md files")
+	files, pkgErrs := run.renderPerPkgReadme(ctx, pkgs)
+	result.Errors = append(result.Errors, pkgErrs...)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	if run.config.SingleFile {
+		// The documentation of the packages is combined into a single file.
+		log.Info("Rendering single file documentation")
+		single, err := run.renderSingleFile(pkgs, files)
+		if err != nil {
+			return result, err
+		}
+		files = []docFile{single}
+	} else {
+		// Render summary DOCS.md
+		log.Info("Rendering summary DOCS.md")
+		summary, err := run.renderSummaryReadme(pkgs)
+		if err != nil {
+			return result, err
+		}
+		files = append(files, summary...)
+	}
+
+	if run.config.Format == FormatMarkdown && run.config.Anchors != AnchorsHTML {
+		slugAnchors(files, run.config.Anchors)
+	}
+
+	for _, f := range files {
+		for _, ref := range f.Unresolved {
+			log.Warn("Unresolved doc link", "path", f.Path, "link", ref)
+			result.Unresolved = append(result.Unresolved, UnresolvedLink{File: f.Path, Link: ref})
+		}
+	}
+
+	if run.config.Check {
+		result.Stale, err = checkDocs(rootDir, files)
+		if err != nil {
+			return result, err
+		}
+		if len(result.Stale) == 0 {
+			log.Info("Documentation is up to date")
+		}
+		return result, nil
+	}
 
-	// Generate summary DOCS.md
-	log.Info("Generating summary DOCS.md")
-	g.generateSummaryReadme(pkgs, rootDir, g.config)
+	result.Files, err = writeDocs(ctx, rootDir, files)
+	return result, err
 }
 
 // hasGoFilesInRoot checks if the root package contains Go files.
@@ -101,143 +328,53 @@ func (g *Gen) hasGoFilesInRoot(pkgs []*common.Pkg) bool {
 	return false
 }
 
-// collectPkgs traverses the directory tree to collect Go packages.
-func (g *Gen) collectPkgs(rootDir string) ([]*common.Pkg, error) {
-	var pkgs []*common.Pkg
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+// collectPkgs loads every package under rootDir and filters out the excluded ones.
+func (g *Gen) collectPkgs(ctx context.Context, rootDir string) ([]*common.Pkg, []*PackageError, error) {
+	loaded, pkgErrs, err := loadPackages(ctx, rootDir, func(pkgPath string) bool {
+		return g.configFor(pkgPath).Unexported
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
 	excludeMap := g.buildExcludeMap()
 
-	walkFn := func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			log.Error("Error accessing path", "path", path, "error", err)
-			return nil // Continue walking
-		}
-
-		// Normalize path to use forward slashes
-		relPath, err := filepath.Rel(rootDir, path)
-		if err != nil {
-			log.Error("Failed to get relative path", "path", path, "error", err)
-			return nil
-		}
-		relPath = filepath.ToSlash(relPath)
+	// Excluded packages aren't documented, whether they compile or not.
+	pkgErrs = slices.DeleteFunc(pkgErrs, func(e *PackageError) bool {
+		return shouldExclude(e.Path, excludeMap)
+	})
 
-		// Check for excluded paths
-		if shouldExclude(relPath, excludeMap) {
-			log.Info("Skipping excluded path", "path", relPath)
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+	var pkgs []*common.Pkg
+	for _, p := range loaded {
+		if shouldExclude(p.Path, excludeMap) {
+			log.Info("Skipping excluded path", "path", p.Path)
+			continue
 		}
-
-		// Skip hidden directories
-		if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
-			log.Info("Skipping hidden directory", "path", path)
-			return filepath.SkipDir
+		p.DocFile = g.docFileName()
+		if g.config.Inject && !g.injectable(g.docPath(p)) {
+			// Nothing links to the documentation that isn't injected.
+			p.DocFile = ""
 		}
 
-		if d.IsDir() {
-			hasGo, err := containsGoFiles(path)
-			if err != nil {
-				log.Error("Failed to check for Go files", "path", path, "error", err)
-				return nil // Continue walking
-			}
-			if hasGo {
-				wg.Add(1)
-				go func(dir string) {
-					defer wg.Done()
-					pk, fs, modName, err := loadPackages(dir, g.config.Unexported)
-					if err != nil {
-						log.Error("Failed to load package", "dir", dir, "error", err)
-						return
-					}
-
-					mu.Lock()
-					defer mu.Unlock()
-
-					packagePath, err := filepath.Rel(rootDir, dir)
-					if err != nil {
-						packagePath = dir // Fallback to absolute path
-					}
-					packagePath = filepath.ToSlash(packagePath)
-					if packagePath == "." {
-						packagePath = "" // Represent root without "."
-					}
-
-					// TODO: check & fix
-					subPkgs := []*common.Pkg{}
-					if !g.config.SkipSubPkgs {
-						for _, imp := range pk.Imports {
-							if !strings.HasPrefix(imp, modName+"/") {
-								continue
-							}
-
-							subPath := strings.TrimPrefix(imp, modName+"/")
-							subPath = fmt.Sprintf("%s/%s", rootDir, subPath)
-
-							pkPath := fmt.Sprintf("%s/%s", rootDir, packagePath)
-							if !strings.Contains(subPath, pkPath) {
-								continue
-							}
-
-							// Corrected: Check subDir instead of subPath
-							if _, err := os.Stat(subPath); os.IsNotExist(err) {
-								log.Warn("Sub-package path does not exist", "path", packagePath, "error", err)
-								continue
-							} else if err != nil {
-								log.Error("Failed to stat sub-package path", "path", packagePath, "error", err)
-								continue
-							}
-
-							log.Info("Collecting sub-packages", "path", packagePath)
-							subSubPkgs, err := g.collectPkgs(packagePath)
-							if err != nil {
-								log.Error("Failed to collect sub-packages", "path", packagePath, "error", err)
-								continue
-							}
-							subPkgs = append(subPkgs, subSubPkgs...)
-						}
-					}
-
-					pkgs = append(pkgs, &common.Pkg{
-						DocFile:  "DOCS.md",
-						FilesSet: fs,
-						Module:   modName,
-						Package:  pk,
-						Path:     packagePath,
-						SubPkgs:  subPkgs,
-					})
-					log.Info("Loaded package", "package", packagePath)
-				}(path)
+		g.deprecated = append(g.deprecated, deprecatedAPIs(p)...)
+		if g.configFor(p.Path).HideDeprecated {
+			if isDeprecated(p.Package.Doc) {
+				log.Info("Skipping deprecated package", "path", p.Path)
+				continue
 			}
+			hideDeprecated(p)
 		}
-
-		return nil
+		pkgs = append(pkgs, p)
 	}
 
-	if err := filepath.WalkDir(rootDir, walkFn); err != nil {
-		return nil, fmt.Errorf("error walking the path %s: %w", rootDir, err)
-	}
-
-	wg.Wait()
-
 	// Sort packages alphabetically by Path
 	sort.Slice(pkgs, func(i, j int) bool {
 		return pkgs[i].Path < pkgs[j].Path
 	})
 
-	// Sort sub-packages if any
-	for _, pkg := range pkgs {
-		if len(pkg.SubPkgs) > 0 {
-			sort.Slice(pkg.SubPkgs, func(a, b int) bool {
-				return pkg.SubPkgs[a].Path < pkg.SubPkgs[b].Path
-			})
-		}
-	}
+	buildPkgTree(pkgs)
 
-	return pkgs, nil
+	return pkgs, pkgErrs, nil
 }
 
 // buildExcludeMap constructs a map for quick exclusion checks.
@@ -260,165 +397,418 @@ func shouldExclude(relPath string, excludeMap map[string]struct{}) bool {
 	return false
 }
 
-// generatePerPkgReadme generates DOCS.md files for each package.
-func (g *Gen) generatePerPkgReadme(allPackages []*common.Pkg, rootDir string, cfg Config) {
+// markdownOptions returns the options used to render the doc comments of pkg,
+// the references that can't be resolved are passed to unresolved.
+func (g *Gen) markdownOptions(pkg *common.Pkg, cfg Config, unresolved func(ref string)) []markdown.Option {
+	// The JSON export holds the deprecation on its own, next to the doc.
+	skipDeprecated := markdown.OptSkipDeprecated(cfg.DeprecationNotice != DeprecationNone && g.config.Format != FormatJSON)
+	if cfg.LegacyMarkdown {
+		return []markdown.Option{markdown.OptUseStdlib(false), skipDeprecated}
+	}
+
+	parser := pkg.Package.Parser()
+	lookupPackage := func(name string) (string, bool) {
+		if importPath, ok := parser.LookupPackage(name); ok {
+			return importPath, true
+		}
+		// Packages of the module not imported by pkg.
+		importPath := g.pkgNames[name]
+		return importPath, importPath != ""
+	}
+	return []markdown.Option{
+		markdown.OptLookup(lookupPackage, parser.LookupSym),
+		markdown.OptDocLinkURL(g.docLinkURL(pkg, pkg, unresolved)),
+		markdown.OptUnresolved(unresolved),
+		skipDeprecated,
+	}
+}
+
+// docLinkURL returns a function resolving the doc links of the comments of pkg
+// to URLs relative to the documentation of from.
+func (g *Gen) docLinkURL(from, pkg *common.Pkg, unresolved func(ref string)) func(link *comment.DocLink) string {
+	return func(link *comment.DocLink) string {
+		qualified := *link
+		if qualified.ImportPath == "" {
+			qualified.ImportPath = pkg.Package.ImportPath
+		}
+		url, ok := g.index.LinkURL(from, &qualified)
+		if !ok {
+			ref := common.SymbolID(link.Recv, link.Name)
+			if link.ImportPath != "" {
+				ref = link.ImportPath + "." + ref
+			}
+			unresolved("[" + ref + "]")
+		}
+		return url
+	}
+}
+
+// typeURL returns a function resolving the URL of the types and packages
+// referenced in the declarations of pkg.
+func (g *Gen) typeURL(pkg *common.Pkg) func(obj types.Object) string {
+	return func(obj types.Object) string {
+		link := &comment.DocLink{}
+		switch obj := obj.(type) {
+		case *types.PkgName:
+			link.ImportPath = obj.Imported().Path()
+		case *types.TypeName:
+			if obj.Pkg() == nil {
+				// Predeclared types such as error or any.
+				return common.PkgGoDevURL + "/builtin#" + obj.Name()
+			}
+			link.ImportPath = obj.Pkg().Path()
+			link.Name = obj.Name()
+		default:
+			return ""
+		}
+
+		url, ok := g.index.LinkURL(pkg, link)
+		if !ok {
+			return ""
+		}
+		return url
+	}
+}
+
+// pkgNames maps the package names to their import path, names shared by
+// several packages map to an empty string.
+func pkgNames(pkgs []*common.Pkg) map[string]string {
+	names := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		if _, ok := names[p.Package.Name]; ok {
+			names[p.Package.Name] = ""
+			continue
+		}
+		names[p.Package.Name] = p.Package.ImportPath
+	}
+	return names
+}
+
+// templateFS returns the custom templates of cfg, or nil when there are none.
+func (g *Gen) templateFS(cfg Config) fs.FS {
+	if cfg.TemplateDir == "" {
+		return nil
+	}
+	return os.DirFS(g.resolvePath(cfg.TemplateDir))
+}
+
+// checkTemplateDirs verifies that the template directories of the configuration
+// and its overrides exist.
+func (g *Gen) checkTemplateDirs() error {
+	dirs := []string{g.config.TemplateDir}
+	for _, o := range g.config.Overrides {
+		dirs = append(dirs, g.configFor(o.Path).TemplateDir)
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		info, err := os.Stat(g.resolvePath(dir))
+		if err != nil {
+			return fmt.Errorf("template directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("template directory %s is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// resolvePath returns p relative to the root directory unless it is absolute.
+func (g *Gen) resolvePath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(g.rootDir, p)
+}
+
+// docFile is a rendered documentation file.
+type docFile struct {
+	// Path of the file relative to the root directory.
+	Path    string
+	Content []byte
+	// Unresolved lists the doc links of the file that couldn't be resolved.
+	Unresolved []string
+	// pkg is the package documented by the file, nil for the summary.
+	pkg *common.Pkg
+	// injected is set when Content is an existing file with the documentation
+	// injected between its markers.
+	injected bool
+}
+
+// renderPerPkgReadme renders the DOCS.md files for each package.
+func (g *Gen) renderPerPkgReadme(ctx context.Context, allPackages []*common.Pkg) ([]docFile, []*PackageError) {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, 10) // Limit concurrency to 10 goroutines
 
-	for _, p := range allPackages {
-		// Optionally, handle root package separately if needed
-		if p.Path == "" && len(p.Package.Filenames) > 0 && !g.config.SkipSubPkgs {
-			// You might want to generate a separate DOCS.md for root if it has Go files
-			// Currently, it's skipped to avoid duplication in summary
+	files := make([]*docFile, len(allPackages))
+	pkgErrs := make([]*PackageError, len(allPackages))
+	for i, p := range allPackages {
+		// The summary documents the root package, in JSON as the first package
+		// of the document of the module. The HTML site, the single file and the
+		// injected files have no summary, the root package is documented like
+		// the others.
+		if p.Path == "" && g.config.Format != FormatHTML && !g.config.SingleFile && !g.config.Inject {
 			continue
 		}
 
 		wg.Add(1)
 		sem <- struct{}{} // Acquire a slot
 
-		go func(pkg *common.Pkg) {
+		go func(i int, pkg *common.Pkg) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release the slot
 
+			if ctx.Err() != nil {
+				return
+			}
+
 			if len(pkg.Package.Filenames) == 0 {
 				log.Warn("No files found for package. Skipping DOCS.md generation.", "package", pkg.Package.Name)
 				return
 			}
 
-			pkgPath := filepath.Join(rootDir, pkg.Path)
-			docsPath := filepath.Join(pkgPath, "DOCS.md")
-			docsPath = filepath.Clean(docsPath)
-
-			// Overwrite existing DOCS.md with a warning
-			if _, err := os.Stat(docsPath); err == nil {
-				log.Warn("DOCS.md already exists. Overwriting.", "path", docsPath)
+			var unresolved []string
+			report := func(ref string) {
+				if !slices.Contains(unresolved, ref) {
+					unresolved = append(unresolved, ref)
+				}
+			}
+			var content []byte
+			var err error
+			if g.config.Inject {
+				content, err = g.injectPkg(pkg, report)
+			} else {
+				content, err = g.renderPkg(pkg, report)
 			}
-
-			file, err := os.Create(docsPath)
 			if err != nil {
-				log.Error("Failed to create DOCS.md", "path", docsPath, "error", err)
+				log.Error("Failed to render documentation", "package", pkg.Package.Name, "error", err)
+				pkgErrs[i] = &PackageError{Path: pkg.Path, Err: err}
 				return
 			}
-			defer file.Close()
-
-			// Execute the template
-			err = template.Execute(file, pkg, cfg)
-			if err != nil {
-				log.Error("Failed to write documentation", "package", pkg.Package.Name, "error", err)
+			if content == nil {
 				return
 			}
 
-			relPath, err := filepath.Rel(rootDir, docsPath)
-			if err != nil {
-				relPath = docsPath
+			files[i] = &docFile{
+				Path:       g.docPath(pkg),
+				Content:    content,
+				Unresolved: unresolved,
+				pkg:        pkg,
+				injected:   g.config.Inject,
 			}
-
-			log.Info("Generated DOCS.md", "package", pkg.Package.Name, "path", relPath)
-		}(p)
+			log.Info("Rendered DOCS.md", "package", pkg.Package.Name, "path", files[i].Path)
+		}(i, p)
 	}
 
 	wg.Wait()
-}
-
-// generateSummaryReadme generates a summary DOCS.md at the root directory.
-func (g *Gen) generateSummaryReadme(allPackages []*common.Pkg, rootDir string, cfg Config) {
-	summaryPath := filepath.Join(rootDir, "DOCS.md")
-	summaryPath = filepath.Clean(summaryPath)
 
-	if _, err := os.Stat(summaryPath); err == nil {
-		log.Warn("Summary DOCS.md already exists. Overwriting.", "path", summaryPath)
+	var rendered []docFile
+	for _, f := range files {
+		if f != nil {
+			rendered = append(rendered, *f)
+		}
 	}
 
-	file, err := os.Create(summaryPath)
-	if err != nil {
-		log.Error("Failed to create summary DOCS.md", "rootDir", rootDir, "error", err)
-		return
+	var errs []*PackageError
+	for _, err := range pkgErrs {
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-	defer file.Close()
-
-	subPackages := filterSubPackages(allPackages)
+	return rendered, errs
+}
 
-	summaryData := template.SummaryData{
-		SubPkgs: subPackages,
+// renderPkg renders the documentation of pkg in the configured format,
+// reporting the doc links that can't be resolved.
+func (g *Gen) renderPkg(pkg *common.Pkg, unresolved func(ref string)) ([]byte, error) {
+	cfg := g.configFor(pkg.Path)
+	opts := g.pkgOptions(pkg, cfg, unresolved)
+	if g.config.Format == FormatJSON {
+		return g.renderJSON(&template.Document{
+			Packages: []*template.Package{template.NewPackage(pkg, cfg.SkipExamples, opts)},
+			Module:   pkg.Module,
+		})
 	}
 
-	err = template.Execute(file, &summaryData, cfg)
-	if err != nil {
-		log.Error("Failed to write summary documentation", "error", err)
-		return
+	var data interface{} = pkg
+	if g.config.Format == FormatHTML {
+		data = g.page(pkg)
 	}
 
-	log.Info("Generated summary DOCS.md", "path", summaryPath)
+	var buf bytes.Buffer
+	if err := template.Execute(&buf, data, cfg, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-// filterSubPackages filters out the root package if necessary.
-func filterSubPackages(allPackages []*common.Pkg) []*common.Pkg {
-	var subPkgs []*common.Pkg
-	for _, p := range allPackages {
-		if p.Path == "" && len(p.Package.Filenames) > 0 {
-			continue
-		}
-		subPkgs = append(subPkgs, p)
+// pkgOptions returns the options of the templates rendering pkg.
+func (g *Gen) pkgOptions(pkg *common.Pkg, cfg Config, unresolved func(ref string)) template.Options {
+	opts := template.Options{
+		Custom:         g.templateFS(cfg),
+		Markdown:       g.markdownOptions(pkg, cfg, unresolved),
+		SourceDir:      g.sourceDir(pkg),
+		SingleFile:     g.config.SingleFile,
+		HideDeprecated: cfg.HideDeprecated,
+	}
+	if cfg.LinkTypes || g.config.Format != FormatMarkdown {
+		opts.TypeURL = g.typeURL(pkg)
+	}
+	if cfg.Implements && g.impls != nil {
+		opts.Implements = g.relations(pkg, g.impls.implements)
+		opts.ImplementedBy = g.relations(pkg, g.impls.implementedBy)
 	}
-	return subPkgs
+	if cfg.PromotedMethods {
+		opts.PromotedMethods = g.promotedMethods(pkg)
+	}
+	return opts
 }
 
-// getArgs retrieves the root directory from command-line arguments or defaults to the current working directory.
-func getArgs(args []string) string {
-	var path string
-	var err error
-	if len(args) > 0 {
-		path = args[0]
-	} else {
-		path, err = os.Getwd()
+// renderSummaryReadme renders the summary DOCS.md of the root directory.
+func (g *Gen) renderSummaryReadme(allPackages []*common.Pkg) ([]docFile, error) {
+	switch g.config.Format {
+	case FormatJSON:
+		f, err := g.renderSummaryJSON(allPackages)
 		if err != nil {
-			log.Fatal("Failed to get current working directory", "error", err)
+			return nil, err
+		}
+		return []docFile{f}, nil
+	case FormatHTML:
+		return g.renderSiteIndex(allPackages)
+	}
+
+	subPackages := topLevelPkgs(allPackages)
+
+	var unresolved []string
+	report := func(ref string) {
+		if !slices.Contains(unresolved, ref) {
+			unresolved = append(unresolved, ref)
+		}
+	}
+	summaryData := template.SummaryData{
+		SubPkgs:    subPackages,
+		Deprecated: g.deprecatedReport(allPackages, report),
+	}
+
+	// The summary documents the package of the root directory, so that the
+	// links to its symbols find their anchors.
+	var cfg interface{} = g.config
+	opts := template.Options{Custom: g.templateFS(g.config)}
+	for _, p := range allPackages {
+		if p.Path == "" && len(p.Package.Filenames) > 0 && !g.config.Inject {
+			rootCfg := g.configFor(p.Path)
+			summaryData.Root = p
+			cfg = rootCfg
+			opts = g.pkgOptions(p, rootCfg, report)
 		}
 	}
 
-	absPath, err := filepath.Abs(path)
+	var buf bytes.Buffer
+	err := template.Execute(&buf, &summaryData, cfg, opts)
 	if err != nil {
-		log.Fatal("Failed to get absolute path", "path", path, "error", err)
+		return nil, fmt.Errorf("rendering summary documentation: %w", err)
 	}
 
-	log.Info("Using root directory", "path", absPath)
-	return absPath
+	summary := docFile{Path: path.Join(g.outDir(), g.docFileName()), Content: buf.Bytes(), Unresolved: unresolved}
+	if g.config.Inject {
+		if g.hasGoFilesInRoot(allPackages) {
+			// The file of the root directory documents its package.
+			return nil, nil
+		}
+		content, err := g.injectFile(summary.Path, func(section string) ([]byte, error) {
+			if section != "" {
+				return nil, fmt.Errorf("section %s needs a package in the root directory", section)
+			}
+			return summary.Content, nil
+		})
+		if content == nil {
+			return nil, err
+		}
+		summary.Content = content
+		summary.injected = true
+	}
+	return []docFile{summary}, nil
 }
 
-// attachSubPkgs attaches sub-packages based on the module name and package imports.
-func (g *Gen) attachSubPkgs(module string, pkg *doc.Package) []*common.Pkg {
-	var pkgs []*common.Pkg
-	for _, imp := range pkg.Imports {
-		if !strings.HasPrefix(imp, module+"/") {
-			continue
+// writeDocs writes the rendered files into the root directory, overwriting
+// existing ones, and returns the paths of the files written.
+func writeDocs(ctx context.Context, rootDir string, files []docFile) ([]string, error) {
+	var written []string
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return written, err
 		}
-		subPath := strings.TrimPrefix(imp, module+"/")
-		subDir := filepath.Join(pkg.ImportPath, subPath)
 
-		if _, err := os.Stat(subDir); os.IsNotExist(err) {
-			log.Warn("Sub-package path does not exist", "path", subDir, "error", err)
-			continue
-		} else if err != nil {
-			log.Error("Failed to stat sub-package path", "path", subDir, "error", err)
-			continue
+		docsPath := filepath.Join(rootDir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(docsPath), 0o755); err != nil {
+			return written, fmt.Errorf("creating documentation directory: %w", err)
 		}
 
-		log.Infof("Collecting sub-packages", "path", subDir)
+		// Overwrite existing files with a warning
+		if _, err := os.Stat(docsPath); err == nil {
+			log.Warn("File already exists. Overwriting.", "path", f.Path)
+		}
 
-		pk, fs, _, err := loadPackages(subPath, g.config.Unexported)
-		if err != nil {
-			log.Error("Failed to collect sub-packages", "path", subDir, "error", err)
+		if err := os.WriteFile(docsPath, f.Content, 0o644); err != nil {
+			return written, fmt.Errorf("writing documentation: %w", err)
+		}
+
+		written = append(written, f.Path)
+		log.Info("Generated documentation", "path", f.Path)
+	}
+	return written, nil
+}
+
+// topLevelPkgs returns the packages listed at the top of the summary tree,
+// the root package is omitted and its sub-packages take its place.
+func topLevelPkgs(allPackages []*common.Pkg) []*common.Pkg {
+	nested := make(map[*common.Pkg]bool)
+	for _, p := range allPackages {
+		if p.Path == "" {
 			continue
 		}
+		for _, sub := range p.SubPkgs {
+			nested[sub] = true
+		}
+	}
 
-		pkgs = append(pkgs, &common.Pkg{
-			DocFile:  "DOCS.md",
-			FilesSet: fs,
-			Module:   module,
-			Package:  pk,
-			Path:     subPath,
-			SubPkgs:  g.attachSubPkgs(module, pk),
-		})
+	var top []*common.Pkg
+	for _, p := range allPackages {
+		if p.Path != "" && !nested[p] {
+			top = append(top, p)
+		}
+	}
+	return top
+}
+
+// buildPkgTree attaches every package to the SubPkgs of the package in its
+// nearest ancestor directory, pkgs must be sorted by Path.
+func buildPkgTree(pkgs []*common.Pkg) {
+	byPath := make(map[string]*common.Pkg, len(pkgs))
+	for _, p := range pkgs {
+		byPath[p.Path] = p
+	}
+
+	for _, p := range pkgs {
+		if parent := parentPkg(p.Path, byPath); parent != nil {
+			parent.SubPkgs = append(parent.SubPkgs, p)
+		}
+	}
+}
+
+// parentPkg returns the package in the nearest ancestor directory of pkgPath.
+func parentPkg(pkgPath string, byPath map[string]*common.Pkg) *common.Pkg {
+	dir := pkgPath
+	for dir != "" {
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+		if parent, ok := byPath[dir]; ok {
+			return parent
+		}
 	}
-	return pkgs
+	return nil
 }
@@ -0,0 +1,225 @@
+package gen
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// allSections includes every section in the documentation, like the
+// default of the gen command.
+var allSections = []string{"constants", "factories", "functions", "methods", "types", "variables"}
+
+// readFile returns the content of the slash separated path relative to dir.
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestGenerateRootPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"m.go": `// Package m is the root package.
+package m
+
+// Root is declared in the root package.
+type Root struct{}
+
+// Old is replaced by [Root].
+//
+// Deprecated: Use [Root] instead.
+func Old() {}
+`,
+		"sub/sub.go": `// Package sub refers to [m.Root].
+package sub
+
+import _ "example.com/m"
+
+// New returns a Sub.
+func New() {}
+
+// Make is replaced by [New].
+//
+// Deprecated: Use [New] or [m.Root] | nil instead.
+func Make() {}
+`,
+	})
+
+	result, err := New(Config{IncludeSections: allSections}).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 || len(result.Unresolved) > 0 {
+		t.Fatalf("Generate() errors = %v, unresolved = %v", result.Errors, result.Unresolved)
+	}
+
+	// The summary documents the root package along with its sub-packages.
+	summary := readFile(t, dir, "DOCS.md")
+	for _, want := range []string{
+		"# Package `m`",
+		`<a id="Root"></a>`,
+		"[sub](sub/DOCS.md)",
+		"[Root](#Root)",
+		// The replacements resolve their links relative to the summary.
+		"| [`m.Old`](#Old) | func | Use [Root](#Root) instead. |",
+		"| [`sub.Make`](sub/DOCS.md#Make) | func | Use [New](sub/DOCS.md#New) or [m.Root](#Root) \\| nil instead. |",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary doesn't contain %q:\n%s", want, summary)
+		}
+	}
+
+	if sub := readFile(t, dir, "sub/DOCS.md"); !strings.Contains(sub, "[m.Root](../DOCS.md#Root)") {
+		t.Errorf("sub/DOCS.md doesn't link to the summary:\n%s", sub)
+	}
+}
+
+// checkGoldenDocs generates the documentation of the testdata module dir with
+// cfg and reports the files that don't match the checked in ones, or writes
+// them with -update.
+func checkGoldenDocs(t *testing.T, dir string, cfg Config) {
+	t.Helper()
+	cfg.Check = !*update
+	result, err := New(cfg).Generate(context.Background(), filepath.Join("testdata", dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 || len(result.Unresolved) > 0 {
+		t.Fatalf("Generate() errors = %v, unresolved = %v", result.Errors, result.Unresolved)
+	}
+	for _, f := range result.Stale {
+		t.Errorf("%s doesn't match the generated documentation, run with -update to rewrite it:\n%s", f.Path, f.Diff)
+	}
+}
+
+// TestGenerateGenerics covers the generic types and functions, their
+// receivers, constraints and instantiations, with plain declarations and with
+// the ones linking their types.
+func TestGenerateGenerics(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		checkGoldenDocs(t, "generics", Config{IncludeSections: allSections})
+	})
+	t.Run("linked", func(t *testing.T) {
+		checkGoldenDocs(t, "generics", Config{IncludeSections: allSections, LinkTypes: true, OutDir: "linked"})
+	})
+}
+
+func TestGenerateExcludedErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":            "module example.com/m\n\ngo 1.21\n",
+		"ok/ok.go":          "// Package ok compiles.\npackage ok\n",
+		"broken/broken.go":  "// Package broken doesn't compile.\npackage broken\n\nvar X int = \"text\"\n",
+		"broken/sub/sub.go": "// Package sub doesn't compile.\npackage sub\n\nvar Y = undefined\n",
+	})
+	cfg := Config{IncludeSections: allSections, ExcludePaths: []string{"broken"}}
+	ctx := context.Background()
+
+	result, err := New(cfg).Generate(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Errorf("Generate() errors = %v, want none for the excluded packages", result.Errors)
+	}
+
+	coverage, err := New(cfg).Coverage(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coverage.Errors) > 0 {
+		t.Errorf("Coverage() errors = %v, want none for the excluded packages", coverage.Errors)
+	}
+
+	lint, err := New(cfg).Lint(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lint.Errors) > 0 {
+		t.Errorf("Lint() errors = %v, want none for the excluded packages", lint.Errors)
+	}
+
+	// Without the exclusion the errors are reported.
+	result, err = New(Config{IncludeSections: allSections}).Generate(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 2 {
+		t.Errorf("Generate() errors = %v, want the ones of broken and broken/sub", result.Errors)
+	}
+}
+
+func TestGeneratePromotedFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"base/base.go": `// Package base declares the embedded struct.
+package base
+
+// Options is used by Base.
+type Options struct{}
+
+// Base is embedded by other packages.
+type Base struct {
+	// Port to listen on.
+	// Default: 8080
+	Port int
+	// Opts configures the server.
+	Opts Options
+	// Old is kept for compatibility.
+	//
+	// Deprecated: Use Port instead.
+	Old int
+}
+`,
+		"server/server.go": `// Package server embeds the struct of package base.
+package server
+
+import "example.com/m/base"
+
+// Server serves.
+type Server struct {
+	base.Base
+}
+`,
+	})
+
+	cfg := Config{IncludeSections: allSections, FieldTables: true, HideDeprecated: true}
+	result, err := New(cfg).Generate(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Generate() errors = %v", result.Errors)
+	}
+
+	server := readFile(t, dir, "server/DOCS.md")
+	for _, want := range []string{
+		"| `Port` | `int` | 8080 | Port to listen on. Promoted from `Base`. |",
+		"| `Opts` | `base.Options` |  | Opts configures the server. Promoted from `Base`. |",
+	} {
+		if !strings.Contains(server, want) {
+			t.Errorf("server/DOCS.md doesn't contain %q:\n%s", want, server)
+		}
+	}
+	if strings.Contains(server, "`Old`") {
+		t.Errorf("server/DOCS.md contains the deprecated field Old:\n%s", server)
+	}
+}
+
+// TestGeneratePromotedMethods covers the methods promoted through value and
+// pointer embedding, from unexported types, from instances of generic types
+// and from other packages.
+func TestGeneratePromotedMethods(t *testing.T) {
+	checkGoldenDocs(t, "promoted", Config{IncludeSections: allSections, PromotedMethods: true})
+}